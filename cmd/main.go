@@ -5,12 +5,16 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"file-ranking/internal/api"
 	"file-ranking/internal/logger"
+	"file-ranking/internal/search"
 	"file-ranking/internal/storage"
+	"file-ranking/internal/validation"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -42,6 +46,31 @@ func main() {
 		log.Error("初始化存储失败: %v", err)
 		os.Exit(1)
 	}
+	if err := store.SetEventRetention(getEventRetention()); err != nil {
+		log.Error("点击事件保留策略无效: %v", err)
+		os.Exit(1)
+	}
+	store.SetUploadValidator(getUploadValidator())
+	if err := store.SetUploadSessionTTL(getUploadSessionTTL()); err != nil {
+		log.Error("上传会话保留时间无效: %v", err)
+		os.Exit(1)
+	}
+	if err := store.SetEditLockTTL(getEditLockTTL()); err != nil {
+		log.Error("编辑锁有效期无效: %v", err)
+		os.Exit(1)
+	}
+	if interval := getCleanupInterval(); interval > 0 {
+		if err := store.StartCleanup(interval); err != nil {
+			log.Error("清理任务启动失败: %v", err)
+			os.Exit(1)
+		}
+	}
+	if cfg, ok := getImportConfig(); ok {
+		if err := store.StartImport(cfg); err != nil {
+			log.Error("导入目录配置无效: %v", err)
+			os.Exit(1)
+		}
+	}
 	defer func() {
 		if err := store.Close(); err != nil {
 			log.Error("关闭存储失败: %v", err)
@@ -59,18 +88,29 @@ func main() {
 	// 配置CORS
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Admin-Token", "X-Client-ID", "Range", "If-Match", "If-None-Match", "If-Modified-Since", "If-Range", "Upload-Offset", "X-Lock-Token"},
+		ExposeHeaders:    []string{"ETag", "Last-Modified", "Content-Range", "Accept-Ranges", "Content-Disposition", "Upload-Offset", "Location"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
+	// 全文索引，随文件变更自动更新
+	searchIndex := search.NewIndex()
+	search.NewIndexer(searchIndex, store)
+	nameIndex := search.NewNameIndex(store)
+
 	// 创建WebSocket Hub
 	hub := api.NewWebSocketHub()
 	go hub.Run()
 
 	// 创建处理器
 	fileHandler := api.NewFileHandler(store)
+	adminHandler := api.NewAdminHandler(store)
+	searchHandler := api.NewSearchHandler(store, searchIndex, nameIndex)
+
+	hub.SetClickHandler(fileHandler.HandleClickEvent)
+	hub.WatchEditLocks(store)
 
 	// 启动实时更新监控
 	go fileHandler.StartRealTimeUpdater(hub)
@@ -91,20 +131,71 @@ func main() {
 
 		// 文件相关API
 		apiGroup.GET("/ranking", fileHandler.GetRanking)
+		apiGroup.GET("/ranking/archive", fileHandler.DownloadRankingArchive)
+		apiGroup.GET("/stats", fileHandler.GetStats)
+		apiGroup.GET("/search", searchHandler.Search)
+		apiGroup.GET("/search/names", searchHandler.LookupNames)
 		apiGroup.GET("/files", fileHandler.GetAllFiles)
 		apiGroup.GET("/files/:id", fileHandler.GetFile)
 		apiGroup.POST("/files/upload", fileHandler.UploadFile)
 		apiGroup.POST("/files/create", fileHandler.CreateFile)
+		apiGroup.POST("/files/archive", fileHandler.DownloadArchive)
+		apiGroup.POST("/uploads", fileHandler.CreateUploadSession)
+		apiGroup.GET("/uploads/:id", fileHandler.GetUploadSession)
+		apiGroup.PATCH("/uploads/:id", fileHandler.UploadChunk)
+		apiGroup.POST("/uploads/:id/finalize", fileHandler.FinalizeUpload)
+		apiGroup.DELETE("/uploads/:id", fileHandler.AbortUpload)
 		apiGroup.POST("/files/:id/click", fileHandler.ClickFile)
 		apiGroup.DELETE("/files/:id", fileHandler.RemoveFile)
 		apiGroup.GET("/files/:id/download", fileHandler.DownloadFile)
+		apiGroup.GET("/files/:id/view", fileHandler.ViewFile)
+		apiGroup.GET("/files/:id/preview", fileHandler.PreviewFile)
 		apiGroup.PUT("/files/:id/rename", fileHandler.RenameFile)
 		apiGroup.GET("/files/:id/content", fileHandler.GetFileContent)
+		apiGroup.GET("/files/:id/stats", fileHandler.GetFileStats)
+		apiGroup.GET("/files/:id/metadata", fileHandler.GetMetadata)
+		apiGroup.PUT("/files/:id/metadata", fileHandler.UpdateMetadata)
+		apiGroup.GET("/tags", fileHandler.GetTags)
 		apiGroup.PUT("/files/:id/content/edit", fileHandler.UpdateFileContent)
+		apiGroup.GET("/files/:id/lock", fileHandler.GetLock)
+		apiGroup.POST("/files/:id/lock", fileHandler.AcquireLock)
+		apiGroup.PUT("/files/:id/lock", fileHandler.RenewLock)
+		apiGroup.DELETE("/files/:id/lock", fileHandler.ReleaseLock)
+		apiGroup.GET("/locks", fileHandler.ListLocks)
+		apiGroup.PUT("/files/:id/move", fileHandler.MoveFile)
+		apiGroup.GET("/folders", fileHandler.ListFolders)
+		apiGroup.POST("/folders", fileHandler.CreateFolder)
+		apiGroup.GET("/folders/:id", fileHandler.GetFolder)
+		apiGroup.GET("/folders/:id/ranking", fileHandler.GetFolderRanking)
+		apiGroup.PUT("/folders/:id/rename", fileHandler.RenameFolder)
+		apiGroup.PUT("/folders/:id/move", fileHandler.MoveFolder)
+		apiGroup.DELETE("/folders/:id", fileHandler.DeleteFolder)
 		apiGroup.POST("/files/click", fileHandler.BulkClick)
+		apiGroup.GET("/clicks/pipeline", fileHandler.GetPipelineStats)
 		apiGroup.GET("/ws", func(c *gin.Context) {
 			hub.HandleWebSocket(c)
 		})
+
+		// 管理API，需要 ADMIN_TOKEN
+		adminGroup := apiGroup.Group("/admin", api.AdminAuth(os.Getenv("ADMIN_TOKEN")))
+		{
+			adminGroup.PUT("/files/:id/clicks", adminHandler.SetClicks)
+			adminGroup.POST("/files/:id/clicks/adjust", adminHandler.AdjustClicks)
+			adminGroup.POST("/clicks/reset", adminHandler.ResetClicks)
+			adminGroup.GET("/audit", adminHandler.GetAuditLog)
+			adminGroup.GET("/duplicates", adminHandler.ListDuplicates)
+			adminGroup.POST("/duplicates/merge", adminHandler.MergeDuplicates)
+			adminGroup.GET("/import", adminHandler.GetImportStatus)
+			adminGroup.GET("/cleanup/policy", adminHandler.GetCleanupPolicy)
+			adminGroup.PUT("/cleanup/policy", adminHandler.SetCleanupPolicy)
+			adminGroup.PUT("/files/:id/expiry", fileHandler.SetExpiry)
+			adminGroup.GET("/cleanup/dry-run", adminHandler.PreviewCleanup)
+			adminGroup.POST("/cleanup/run", adminHandler.RunCleanup)
+			adminGroup.GET("/cleanup/last", adminHandler.GetLastCleanup)
+			adminGroup.POST("/import/scan", adminHandler.ScanImport)
+			adminGroup.GET("/events", adminHandler.QueryClickEvents)
+			adminGroup.POST("/events/prune", adminHandler.PruneClickEvents)
+		}
 	}
 
 	// 静态文件服务 - 支持新的目录结构
@@ -153,6 +244,94 @@ func main() {
 	log.Info("✅ 服务器已关闭")
 }
 
+// 点击事件保留策略，默认保留30天原始事件，过期后按天汇总
+func getEventRetention() storage.EventRetention {
+	policy := storage.EventRetention{
+		MaxAge: 30 * 24 * time.Hour,
+		Mode:   storage.RetentionAggregate,
+	}
+	if days, err := strconv.Atoi(os.Getenv("CLICK_EVENT_RETENTION_DAYS")); err == nil && days >= 0 {
+		policy.MaxAge = time.Duration(days) * 24 * time.Hour
+	}
+	if mode := os.Getenv("CLICK_EVENT_RETENTION_MODE"); mode != "" {
+		policy.Mode = mode
+	}
+	return policy
+}
+
+// 未完成的分片上传默认保留24小时
+func getUploadSessionTTL() time.Duration {
+	if hours, err := strconv.Atoi(os.Getenv("UPLOAD_SESSION_TTL_HOURS")); err == nil {
+		return time.Duration(hours) * time.Hour
+	}
+	return storage.DefaultUploadSessionTTL
+}
+
+// 编辑锁默认有效期2分钟，编辑页面会在到期前自动续期
+func getEditLockTTL() time.Duration {
+	if seconds, err := strconv.Atoi(os.Getenv("EDIT_LOCK_TTL_SECONDS")); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	return storage.DefaultEditLockTTL
+}
+
+// 清理任务默认每60分钟执行一次，CLEANUP_INTERVAL_MINUTES=0 时不在后台执行
+func getCleanupInterval() time.Duration {
+	if minutes, err := strconv.Atoi(os.Getenv("CLEANUP_INTERVAL_MINUTES")); err == nil && minutes >= 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return storage.DefaultCleanupInterval
+}
+
+// 导入目录，设置 IMPORT_DIR 后启用，默认每10秒扫描一次，源文件删除后移入回收站
+func getImportConfig() (storage.ImportConfig, bool) {
+	dir := os.Getenv("IMPORT_DIR")
+	if dir == "" {
+		return storage.ImportConfig{}, false
+	}
+	cfg := storage.ImportConfig{
+		Dir:        dir,
+		Interval:   storage.DefaultImportInterval,
+		DeleteMode: os.Getenv("IMPORT_DELETE_MODE"),
+	}
+	if seconds, err := strconv.Atoi(os.Getenv("IMPORT_INTERVAL_SECONDS")); err == nil && seconds > 0 {
+		cfg.Interval = time.Duration(seconds) * time.Second
+	}
+	return cfg, true
+}
+
+// 上传校验链：大小限制、扩展名黑白名单、文件头检查和可选的外部扫描命令
+func getUploadValidator() validation.Validator {
+	limit := validation.SizeLimit{Max: validation.DefaultMaxSize, ResumableMax: storage.MaxResumableSize}
+	if mb, err := strconv.ParseInt(os.Getenv("UPLOAD_MAX_SIZE_MB"), 10, 64); err == nil && mb >= 0 {
+		limit.Max = mb << 20
+	}
+	if mb, err := strconv.ParseInt(os.Getenv("UPLOAD_MAX_RESUMABLE_SIZE_MB"), 10, 64); err == nil && mb > 0 && mb<<20 < storage.MaxResumableSize {
+		limit.ResumableMax = mb << 20
+	}
+
+	chain := validation.Chain{
+		limit,
+		validation.Extensions{
+			Allow: validation.ParseExtensions(os.Getenv("UPLOAD_ALLOWED_EXTENSIONS")),
+			Deny:  validation.ParseExtensions(os.Getenv("UPLOAD_DENIED_EXTENSIONS")),
+		},
+	}
+	if os.Getenv("UPLOAD_CHECK_MAGIC") != "false" {
+		chain = append(chain, validation.MagicBytes{})
+	}
+
+	timeout := validation.DefaultScanTimeout
+	if seconds, err := strconv.Atoi(os.Getenv("UPLOAD_SCAN_TIMEOUT_SECONDS")); err == nil && seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	}
+	if scanner := validation.ParseScanner(os.Getenv("UPLOAD_SCAN_COMMAND"), timeout); scanner != nil {
+		logger.GetInstance().Info("🛡️ 上传文件扫描命令: %s", strings.Join(scanner.Command, " "))
+		chain = append(chain, scanner)
+	}
+	return chain
+}
+
 func getPort() string {
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -0,0 +1,331 @@
+package api
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"file-ranking/internal/logger"
+	"file-ranking/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminHandler struct {
+	store *storage.FileStore
+}
+
+func NewAdminHandler(store *storage.FileStore) *AdminHandler {
+	return &AdminHandler{
+		store: store,
+	}
+}
+
+// AdminAuth 校验管理员令牌，令牌为空时禁用所有管理接口
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"status":  "error",
+				"message": "管理接口未启用，请设置 ADMIN_TOKEN",
+			})
+			return
+		}
+
+		provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if provided == "" {
+			provided = c.GetHeader("X-Admin-Token")
+		}
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			logger.GetInstance().Warn("⚠️ 管理接口鉴权失败: %s %s (来源: %s)", c.Request.Method, c.Request.URL.Path, c.ClientIP())
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"status":  "error",
+				"message": "管理员令牌无效",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+func (h *AdminHandler) SetClicks(c *gin.Context) {
+	fileID := c.Param("id")
+
+	var req struct {
+		Clicks *int   `json:"clicks" binding:"required"`
+		Reason string `json:"reason" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	file, err := h.store.SetClicks(fileID, *req.Clicks, req.Reason, c.ClientIP())
+	if err != nil {
+		c.JSON(clickErrorStatus(h.store, fileID), gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    file,
+		"message": "点击数设置成功",
+	})
+}
+
+func (h *AdminHandler) AdjustClicks(c *gin.Context) {
+	fileID := c.Param("id")
+
+	var req struct {
+		Delta  int    `json:"delta" binding:"required"`
+		Reason string `json:"reason" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	file, err := h.store.AdjustClicks(fileID, req.Delta, req.Reason, c.ClientIP())
+	if err != nil {
+		c.JSON(clickErrorStatus(h.store, fileID), gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    file,
+		"message": "点击数调整成功",
+	})
+}
+
+func (h *AdminHandler) ResetClicks(c *gin.Context) {
+	var req struct {
+		All    bool               `json:"all"`
+		Filter storage.FileFilter `json:"filter"`
+		Reason string             `json:"reason" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	// 清空全部计数必须显式指定 all
+	if req.All == !req.Filter.IsEmpty() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "必须指定 all 或 filter 其中之一",
+		})
+		return
+	}
+
+	entries, err := h.store.ResetClicks(req.Filter, req.Reason, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data": gin.H{
+			"reset":   len(entries),
+			"entries": entries,
+		},
+		"message": "点击数重置成功",
+	})
+}
+
+func (h *AdminHandler) GetAuditLog(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+	entries, err := h.store.GetAuditLog(limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    entries,
+		"message": "获取审计日志成功",
+	})
+}
+
+// ListDuplicates 列出内容相同的文件组，每组第一个为合并时保留的文件
+func (h *AdminHandler) ListDuplicates(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    h.store.ListDuplicates(),
+		"message": "获取重复文件成功",
+	})
+}
+
+// MergeDuplicates 合并内容相同的文件，点击数并入保留的文件，hashes为空时合并全部
+func (h *AdminHandler) MergeDuplicates(c *gin.Context) {
+	var req struct {
+		Hashes []string `json:"hashes"`
+		Reason string   `json:"reason" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	merged, err := h.store.MergeDuplicates(req.Hashes, req.Reason, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	removed := 0
+	for _, group := range merged {
+		removed += len(group.Removed)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data": gin.H{
+			"groups":  merged,
+			"merged":  len(merged),
+			"removed": removed,
+		},
+		"message": "重复文件合并成功",
+	})
+}
+
+// 文件不存在返回404，其余校验错误返回400
+func clickErrorStatus(store *storage.FileStore, id string) int {
+	if _, exists := store.GetFile(id); !exists {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+// GetImportStatus 导入目录的同步状态
+func (h *AdminHandler) GetImportStatus(c *gin.Context) {
+	status, err := h.store.GetImportStatus()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    status,
+		"message": "获取导入状态成功",
+	})
+}
+
+// ScanImport 立即扫描导入目录
+func (h *AdminHandler) ScanImport(c *gin.Context) {
+	result, err := h.store.ScanImport()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    result,
+		"message": "导入目录扫描完成",
+	})
+}
+
+// GetCleanupPolicy 当前的清理策略
+func (h *AdminHandler) GetCleanupPolicy(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    h.store.GetCleanupPolicy(),
+		"message": "获取清理策略成功",
+	})
+}
+
+// SetCleanupPolicy 保存清理策略，下次清理时生效
+func (h *AdminHandler) SetCleanupPolicy(c *gin.Context) {
+	var policy storage.CleanupPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.store.SetCleanupPolicy(policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    h.store.GetCleanupPolicy(),
+		"message": "清理策略已保存",
+	})
+}
+
+// PreviewCleanup 预演清理：列出按当前策略将被删除或归档的文件，不做任何修改
+func (h *AdminHandler) PreviewCleanup(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    h.store.PlanCleanup(time.Now()),
+		"message": "清理预演完成",
+	})
+}
+
+// RunCleanup 立即按当前策略执行清理
+func (h *AdminHandler) RunCleanup(c *gin.Context) {
+	report := h.store.RunCleanup(time.Now(), c.ClientIP())
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    report,
+		"message": fmt.Sprintf("清理完成: 删除 %d 个，归档 %d 个，失败 %d 个", report.Deleted, report.Archived, report.Failed),
+	})
+}
+
+// GetLastCleanup 最近一次执行的清理结果
+func (h *AdminHandler) GetLastCleanup(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    h.store.LastCleanup(),
+		"message": "获取清理结果成功",
+	})
+}
@@ -0,0 +1,223 @@
+package api
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"file-ranking/internal/logger"
+	"file-ranking/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 打包下载的数量限制
+const (
+	maxArchiveFiles      = 1000
+	defaultArchiveTopN   = 10
+	maxArchiveRankingTop = 100
+)
+
+// archiveManifestEntry 排行榜打包中每个文档的排名信息
+type archiveManifestEntry struct {
+	Rank   int    `json:"rank"`
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Clicks int    `json:"clicks"`
+	Size   int64  `json:"size"`
+	// 在压缩包中的路径，文件缺失时为空
+	Path  string `json:"path,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type archiveManifest struct {
+	GeneratedAt time.Time              `json:"generated_at"`
+	Limit       int                    `json:"limit"`
+	Tags        []string               `json:"tags,omitempty"`
+	Files       []archiveManifestEntry `json:"files"`
+}
+
+// DownloadArchive 把选中的文件打包为zip，边读边写，不在内存中构建整个压缩包
+func (h *FileHandler) DownloadArchive(c *gin.Context) {
+	log := logger.GetInstance()
+
+	var req struct {
+		IDs []string `json:"ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.IDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "请选择要下载的文件",
+		})
+		return
+	}
+	if len(req.IDs) > maxArchiveFiles {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": fmt.Sprintf("一次最多打包 %d 个文件", maxArchiveFiles),
+		})
+		return
+	}
+
+	seen := make(map[string]bool, len(req.IDs))
+	files := make([]*storage.FileData, 0, len(req.IDs))
+	var missing []string
+	for _, id := range req.IDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		file, exists := h.store.GetFile(id)
+		if !exists {
+			missing = append(missing, id)
+			continue
+		}
+		files = append(files, file)
+	}
+	if len(missing) > 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"data":    gin.H{"missing": missing},
+			"message": fmt.Sprintf("%d 个文件不存在", len(missing)),
+		})
+		return
+	}
+
+	log.Info("📦 打包下载 %d 个文件", len(files))
+	zw := startZipResponse(c, "files")
+	names := make(map[string]bool, len(files))
+	for _, file := range files {
+		if _, err := writeZipFile(zw, uniqueEntryName(names, archivePath(file.Name)), file); err != nil {
+			log.Error("❌ 打包文件失败: %s - %v", file.Name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		log.Error("❌ 打包下载失败: %v", err)
+	}
+}
+
+// DownloadRankingArchive 打包排行榜前N个文档，附带记录排名和点击数的 manifest.json
+func (h *FileHandler) DownloadRankingArchive(c *gin.Context) {
+	log := logger.GetInstance()
+
+	limit := defaultArchiveTopN
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxArchiveRankingTop {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": fmt.Sprintf("limit 必须是 1-%d 之间的整数", maxArchiveRankingTop),
+			})
+			return
+		}
+		limit = n
+	}
+
+	tags := tagsFromQuery(c)
+	ranking := filterByTags(h.store.GetRanking(), tags)
+	if len(ranking) > limit {
+		ranking = ranking[:limit]
+	}
+
+	log.Info("📦 打包下载排行榜前 %d 个文档", len(ranking))
+	zw := startZipResponse(c, "ranking_top"+strconv.Itoa(limit))
+	manifest := archiveManifest{
+		GeneratedAt: time.Now(),
+		Limit:       limit,
+		Tags:        tags,
+		Files:       make([]archiveManifestEntry, 0, len(ranking)),
+	}
+	width := len(strconv.Itoa(len(ranking)))
+	names := make(map[string]bool, len(ranking))
+	for i := range ranking {
+		file := &ranking[i]
+		entry := archiveManifestEntry{
+			Rank:   i + 1,
+			ID:     file.ID,
+			Name:   file.Name,
+			Clicks: file.Clicks,
+			Size:   file.Size,
+		}
+		// 文件名前加排名，解压后按名称排序即为排行顺序
+		name := fmt.Sprintf("%0*d_%s", width, i+1, strings.ReplaceAll(archivePath(file.Name), "/", "_"))
+		name = uniqueEntryName(names, name)
+		if written, err := writeZipFile(zw, name, file); err != nil {
+			log.Error("❌ 打包文件失败: %s - %v", file.Name, err)
+			if !written {
+				entry.Error = "文件读取失败"
+			}
+		} else {
+			entry.Path = name
+		}
+		manifest.Files = append(manifest.Files, entry)
+	}
+
+	// manifest 最后写入，记录实际打包的结果
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: uniqueEntryName(names, "manifest.json"), Method: zip.Deflate, Modified: manifest.GeneratedAt})
+	if err == nil {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(manifest)
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		log.Error("❌ 打包下载失败: %v", err)
+	}
+}
+
+// 写响应头并返回直接写入响应的zip写入器，之后无法再返回错误状态码
+func startZipResponse(c *gin.Context, prefix string) *zip.Writer {
+	filename := fmt.Sprintf("%s_%s.zip", prefix, time.Now().Format("20060102_150405"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+	return zip.NewWriter(c.Writer)
+}
+
+// 把文件写入压缩包；打开文件失败时不创建条目，written为false
+func writeZipFile(zw *zip.Writer, name string, file *storage.FileData) (written bool, err error) {
+	f, err := os.Open(file.Path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: file.ModifiedAt})
+	if err != nil {
+		return false, err
+	}
+	_, err = io.Copy(w, f)
+	return true, err
+}
+
+// 文件名中的 / 保留为目录，去掉 .. 等跳出压缩包的路径
+func archivePath(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, "\\", "/")), "/")
+	if name == "" {
+		return "unnamed"
+	}
+	return name
+}
+
+// 重名时在扩展名前加序号：a.txt、a (2).txt
+func uniqueEntryName(used map[string]bool, name string) string {
+	candidate := name
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 2; used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+	used[candidate] = true
+	return candidate
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"file-ranking/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 从请求中提取点击事件的元数据，客户端标识优先使用 X-Client-ID
+func newClickEvent(c *gin.Context, fileID, source string, count int) storage.ClickEvent {
+	client := c.GetHeader("X-Client-ID")
+	if client == "" {
+		client = c.Query("client_id")
+	}
+	if client == "" {
+		client = c.ClientIP()
+	}
+	return storage.ClickEvent{
+		Time:      time.Now(),
+		FileID:    fileID,
+		Count:     count,
+		Client:    client,
+		UserAgent: c.Request.UserAgent(),
+		Referrer:  c.Request.Referer(),
+		Source:    source,
+	}
+}
+
+// HandleClickEvent 处理非HTTP来源（如WebSocket）的点击
+func (h *FileHandler) HandleClickEvent(ev storage.ClickEvent) error {
+	if err := h.store.IncrementClick(ev.FileID); err != nil {
+		return err
+	}
+	ev.Time = time.Now()
+	h.store.RecordClickEvents(ev)
+	return nil
+}
+
+func (h *AdminHandler) QueryClickEvents(c *gin.Context) {
+	q := storage.EventQuery{
+		FileID: c.Query("file_id"),
+		Client: c.Query("client"),
+		Source: c.Query("source"),
+	}
+	q.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "1000"))
+
+	for param, dst := range map[string]*time.Time{"from": &q.From, "to": &q.To} {
+		v := c.Query(param)
+		if v == "" {
+			continue
+		}
+		t, err := parseTimeParam(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": "参数错误: " + err.Error(),
+			})
+			return
+		}
+		*dst = t
+	}
+
+	result, err := h.store.QueryClickEvents(q)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    result,
+		"message": "获取点击事件成功",
+	})
+}
+
+func (h *AdminHandler) PruneClickEvents(c *gin.Context) {
+	report, err := h.store.PruneClickEvents(time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    report,
+		"message": "点击事件清理完成",
+	})
+}
@@ -1,11 +1,19 @@
 package api
 
 import (
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"file-ranking/internal/logger"
 	"file-ranking/internal/storage"
+	"file-ranking/internal/validation"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,10 +28,102 @@ func NewFileHandler(store *storage.FileStore) *FileHandler {
 	}
 }
 
+// 解压上传的压缩包大小限制，单个文件的大小由校验链限制
+const maxArchiveUploadSize = 100 * 1024 * 1024
+
+// uploadResult 批量上传中单个文件的结果
+type uploadResult struct {
+	Name    string            `json:"name"`
+	Status  string            `json:"status"`
+	Data    *storage.FileData `json:"data,omitempty"`
+	Message string            `json:"message,omitempty"`
+	// 从压缩包解压出的文件记录来源压缩包
+	Archive string `json:"archive,omitempty"`
+	// 未通过上传校验的原因
+	Reasons []validation.Reason `json:"reasons,omitempty"`
+	// 内容与已有文件相同时的处理结果
+	Duplicate *duplicateInfo `json:"duplicate,omitempty"`
+
+	action     string
+	httpStatus int
+}
+
+// duplicateInfo 内容相同的已有文件及本次上传的处理方式
+type duplicateInfo struct {
+	Action string             `json:"action"`
+	Files  []storage.FileData `json:"files"`
+}
+
+// 上传成功的结果；替换或丢弃时没有新建文件，返回200
+func succeededUpload(name string, outcome *storage.UploadOutcome) uploadResult {
+	r := uploadResult{Name: name, Status: "success", Data: outcome.File, Message: uploadMessage(outcome.Action), action: outcome.Action, httpStatus: http.StatusCreated}
+	if len(outcome.Duplicates) > 0 {
+		r.Duplicate = &duplicateInfo{Action: outcome.Action, Files: outcome.Duplicates}
+	}
+	if outcome.Action == storage.UploadReplaced || outcome.Action == storage.UploadDiscarded {
+		r.httpStatus = http.StatusOK
+	}
+	return r
+}
+
+// 为本次上传新建的文件设置到期时间；替换或丢弃时已有文件的到期时间不变
+func (h *FileHandler) applyExpiry(r *uploadResult, expiresAt *time.Time) {
+	if expiresAt == nil || r.Status != "success" || (r.action != storage.UploadCreated && r.action != storage.UploadKept) {
+		return
+	}
+	if file, err := h.store.SetFileExpiry(r.Data.ID, expiresAt); err == nil {
+		r.Data = file
+	}
+}
+
+func uploadMessage(action string) string {
+	switch action {
+	case storage.UploadKept:
+		return "文件上传成功，内容与已有文件相同"
+	case storage.UploadReplaced:
+		return "内容与已有文件相同，已替换已有文件"
+	case storage.UploadDiscarded:
+		return "内容与已有文件相同，已丢弃本次上传"
+	}
+	return "文件上传成功"
+}
+
+// 重复内容的处理方式，默认保留两份
+func duplicatePolicy(c *gin.Context) (string, bool) {
+	policy := c.PostForm("on_duplicate")
+	if policy == "" {
+		policy = c.DefaultQuery("on_duplicate", storage.DuplicateKeep)
+	}
+	if !storage.ValidDuplicatePolicy(policy) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "on_duplicate 必须是 keep、replace 或 discard",
+		})
+		return "", false
+	}
+	return policy, true
+}
+
+// 上传失败的结果，未通过校验时附带拒绝原因
+func failedUpload(name, prefix string, err error) uploadResult {
+	var verr *validation.Error
+	if errors.As(err, &verr) {
+		return uploadResult{Name: name, Status: "error", Message: err.Error(), Reasons: verr.Reasons, httpStatus: http.StatusUnprocessableEntity}
+	}
+	return uploadResult{Name: name, Status: "error", Message: prefix + err.Error(), httpStatus: http.StatusInternalServerError}
+}
+
+// UploadFile 一次请求可以包含多个 file 字段；extract=true 时解压 zip/tar.gz 压缩包，
+// 每个文件登记为独立文档；on_duplicate 指定内容与已有文件相同时的处理方式，
+// expires_at/expires_in 为新建的文件设置到期时间。
+// 只上传一个普通文件时保持原有的响应格式
 func (h *FileHandler) UploadFile(c *gin.Context) {
 	log := logger.GetInstance()
-	file, header, err := c.Request.FormFile("file")
-	if err != nil {
+	form, err := c.MultipartForm()
+	if err != nil || len(form.File["file"]) == 0 {
+		if err == nil {
+			err = http.ErrMissingFile
+		}
 		log.Error("❌ 上传请求错误: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{
 			"status":  "error",
@@ -31,39 +131,149 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 		})
 		return
 	}
-	defer file.Close()
+	headers := form.File["file"]
+	extract := c.PostForm("extract") == "true" || c.Query("extract") == "true"
+	policy, ok := duplicatePolicy(c)
+	if !ok {
+		return
+	}
+	expiresAt, ok := uploadExpiry(c)
+	if !ok {
+		return
+	}
 
-	log.Info("📤 收到上传请求: %s (大小: %d bytes)", header.Filename, header.Size)
+	if len(headers) == 1 && !(extract && storage.IsArchive(headers[0].Filename)) {
+		h.uploadSingle(c, headers[0], policy, expiresAt)
+		return
+	}
 
-	if header.Size > 10*1024*1024 { // 10MB限制
-		log.Warn("⚠️ 文件过大: %s (%d bytes)", header.Filename, header.Size)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  "error",
-			"message": "文件大小不能超过10MB",
-		})
+	var results []uploadResult
+	succeeded := 0
+	for _, header := range headers {
+		var batch []uploadResult
+		if extract && storage.IsArchive(header.Filename) {
+			batch = h.uploadArchive(header, policy)
+		} else {
+			batch = []uploadResult{h.uploadOne(header, policy)}
+		}
+		for i := range batch {
+			h.applyExpiry(&batch[i], expiresAt)
+			if batch[i].Status == "success" {
+				succeeded++
+			}
+		}
+		results = append(results, batch...)
+	}
+
+	status := http.StatusCreated
+	respStatus := "success"
+	if succeeded == 0 {
+		status = http.StatusBadRequest
+		respStatus = "error"
+	}
+	log.Info("📤 批量上传完成: 成功 %d 个，失败 %d 个", succeeded, len(results)-succeeded)
+	c.JSON(status, gin.H{
+		"status": respStatus,
+		"data": gin.H{
+			"results":   results,
+			"succeeded": succeeded,
+			"failed":    len(results) - succeeded,
+		},
+		"message": fmt.Sprintf("上传完成: 成功 %d 个，失败 %d 个", succeeded, len(results)-succeeded),
+	})
+}
+
+func respondUploadError(c *gin.Context, result uploadResult) {
+	resp := gin.H{
+		"status":  "error",
+		"message": result.Message,
+	}
+	if len(result.Reasons) > 0 {
+		resp["data"] = gin.H{"reasons": result.Reasons}
+	}
+	c.JSON(result.httpStatus, resp)
+}
+
+func (h *FileHandler) uploadSingle(c *gin.Context, header *multipart.FileHeader, policy string, expiresAt *time.Time) {
+	log := logger.GetInstance()
+	result := h.uploadOne(header, policy)
+	if result.Status != "success" {
+		respondUploadError(c, result)
 		return
 	}
+	h.applyExpiry(&result, expiresAt)
 
-	fileData, err := h.store.UploadFile(header.Filename, file)
+	log.Info("✅ 上传成功: %s (ID: %s)", result.Data.Name, result.Data.ID)
+	respondUploaded(c, result)
+}
+
+// 单个文件的上传响应，内容重复时附带 duplicate
+func respondUploaded(c *gin.Context, result uploadResult) {
+	resp := gin.H{
+		"status":  "success",
+		"data":    result.Data,
+		"message": result.Message,
+	}
+	if result.Duplicate != nil {
+		resp["duplicate"] = result.Duplicate
+	}
+	c.JSON(result.httpStatus, resp)
+}
+
+func (h *FileHandler) uploadOne(header *multipart.FileHeader, policy string) uploadResult {
+	log := logger.GetInstance()
+	log.Info("📤 收到上传请求: %s (大小: %d bytes)", header.Filename, header.Size)
+
+	if err := h.store.PrecheckUpload(header.Filename, header.Size, false); err != nil {
+		return failedUpload(header.Filename, "", err)
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		log.Error("❌ 上传请求错误: %v", err)
+		return uploadResult{Name: header.Filename, Status: "error", Message: "获取文件失败: " + err.Error(), httpStatus: http.StatusBadRequest}
+	}
+	defer file.Close()
+
+	outcome, err := h.store.UploadFileWithPolicy(header.Filename, file, policy)
 	if err != nil {
 		log.Error("❌ 上传失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  "error",
-			"message": "上传失败: " + err.Error(),
-		})
-		return
+		return failedUpload(header.Filename, "上传失败: ", err)
 	}
+	return succeededUpload(header.Filename, outcome)
+}
 
-	log.Info("✅ 上传成功: %s (ID: %s)", fileData.Name, fileData.ID)
-	c.JSON(http.StatusCreated, gin.H{
-		"status":  "success",
-		"data":    fileData,
-		"message": "文件上传成功",
-	})
+func (h *FileHandler) uploadArchive(header *multipart.FileHeader, policy string) []uploadResult {
+	if header.Size > maxArchiveUploadSize {
+		return []uploadResult{{Name: header.Filename, Status: "error", Message: "压缩包大小不能超过100MB"}}
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return []uploadResult{{Name: header.Filename, Status: "error", Message: "获取文件失败: " + err.Error()}}
+	}
+	defer file.Close()
+
+	entries, err := h.store.ImportArchive(header.Filename, file, header.Size, policy)
+	results := make([]uploadResult, 0, len(entries)+1)
+	for _, entry := range entries {
+		var r uploadResult
+		if entry.Err != nil {
+			r = failedUpload(entry.Name, "", entry.Err)
+		} else {
+			r = succeededUpload(entry.Name, entry.Outcome)
+		}
+		r.Archive = header.Filename
+		results = append(results, r)
+	}
+	if err != nil {
+		results = append(results, uploadResult{Name: header.Filename, Status: "error", Message: err.Error()})
+	}
+	return results
 }
 
 func (h *FileHandler) GetRanking(c *gin.Context) {
-	ranking := h.store.GetRanking()
+	ranking := filterByTags(h.store.GetRanking(), tagsFromQuery(c))
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "success",
 		"data":    ranking,
@@ -85,7 +295,14 @@ func (h *FileHandler) ClickFile(c *gin.Context) {
 
 	log.Info("👆 收到点击请求: %s", fileID)
 
-	if err := h.store.IncrementClick(fileID); err != nil {
+	// wait=true 时等待点击提交到排行榜后再返回
+	var err error
+	if c.Query("wait") == "true" {
+		err = h.store.SubmitClicks(fileID, 1, true)
+	} else {
+		err = h.store.IncrementClick(fileID)
+	}
+	if err != nil {
 		log.Error("❌ 点击失败: %v", err)
 		c.JSON(http.StatusNotFound, gin.H{
 			"status":  "error",
@@ -94,6 +311,8 @@ func (h *FileHandler) ClickFile(c *gin.Context) {
 		return
 	}
 
+	h.store.RecordClickEvents(newClickEvent(c, fileID, storage.SourceREST, 0))
+
 	file, _ := h.store.GetFile(fileID)
 	log.Info("✅ 点击成功: %s (当前点击: %d)", file.Name, file.Clicks)
 	c.JSON(http.StatusOK, gin.H{
@@ -103,6 +322,14 @@ func (h *FileHandler) ClickFile(c *gin.Context) {
 	})
 }
 
+func (h *FileHandler) GetPipelineStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    h.store.GetPipelineStats(),
+		"message": "获取点击管道状态成功",
+	})
+}
+
 func (h *FileHandler) DownloadFile(c *gin.Context) {
 	fileID := c.Param("id")
 	if fileID == "" {
@@ -122,7 +349,116 @@ func (h *FileHandler) DownloadFile(c *gin.Context) {
 		return
 	}
 
-	c.FileAttachment(file.Path, file.Name)
+	serveFile(c, file, "attachment")
+}
+
+// ViewFile 在浏览器中直接打开文件
+func (h *FileHandler) ViewFile(c *gin.Context) {
+	file, exists := h.store.GetFile(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "文件不存在",
+		})
+		return
+	}
+
+	c.Header("X-Content-Type-Options", "nosniff")
+	// 上传的HTML、SVG可能包含脚本，放在沙箱中打开
+	c.Header("Content-Security-Policy", "sandbox")
+	serveFile(c, file, "inline")
+}
+
+// 发送文件内容，ETag取内容哈希，Last-Modified取内容修改时间；
+// 条件请求（If-None-Match、If-Modified-Since、If-Range）和多段Range由http.ServeContent处理
+func serveFile(c *gin.Context, file *storage.FileData, disposition string) {
+	f, err := os.Open(file.Path)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "文件不存在",
+		})
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "读取文件失败: " + err.Error(),
+		})
+		return
+	}
+
+	// 磁盘上的文件与记录不一致（正在被修改）时不给出校验值，避免缓存错误的内容
+	if file.Hash != "" && info.Size() == file.Size {
+		c.Header("ETag", `"`+file.Hash+`"`)
+	}
+	c.Header("Content-Type", contentType(file))
+	c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": file.Name}))
+	c.Header("Cache-Control", "no-cache")
+
+	modified := file.ModifiedAt
+	if modified.IsZero() {
+		modified = info.ModTime()
+	}
+	http.ServeContent(c.Writer, c.Request, file.Name, modified, f)
+}
+
+func contentType(file *storage.FileData) string {
+	if file.MimeType == "" {
+		return "application/octet-stream"
+	}
+	return file.MimeType
+}
+
+// 文件信息的ETag取版本号，与下载内容时取内容哈希的ETag区分
+func versionETag(version int) string {
+	return fmt.Sprintf(`"v%d"`, version)
+}
+
+// 写操作基于的文件版本：优先取 If-Match 请求头，其次取请求中的 version；
+// 都没有时返回428，格式错误时返回400，此时ok为false且已写入响应
+func expectedVersion(c *gin.Context, version int) (int, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		if version > 0 {
+			return version, true
+		}
+		c.JSON(http.StatusPreconditionRequired, gin.H{
+			"status":  "error",
+			"message": "缺少 If-Match 请求头或 version 字段，请先获取文件的当前版本",
+		})
+		return 0, false
+	}
+	if header == "*" {
+		return storage.AnyVersion, true
+	}
+
+	tag := strings.Trim(strings.TrimPrefix(header, "W/"), `"`)
+	n, err := strconv.Atoi(strings.TrimPrefix(tag, "v"))
+	if err != nil || n <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "If-Match 格式错误，应为文件信息返回的 ETag，如 \"v3\"",
+		})
+		return 0, false
+	}
+	return n, true
+}
+
+// 版本冲突时返回412和文件的当前信息，客户端据此提示用户并重新加载
+func (h *FileHandler) respondVersionConflict(c *gin.Context, fileID string, err error) {
+	file, _ := h.store.GetFile(fileID)
+	if file != nil {
+		c.Header("ETag", versionETag(file.Version))
+	}
+	c.JSON(http.StatusPreconditionFailed, gin.H{
+		"status":  "error",
+		"data":    file,
+		"message": err.Error(),
+	})
 }
 
 func (h *FileHandler) RemoveFile(c *gin.Context) {
@@ -135,7 +471,20 @@ func (h *FileHandler) RemoveFile(c *gin.Context) {
 		return
 	}
 
-	if err := h.store.RemoveFile(fileID); err != nil {
+	var version int
+	if v := c.Query("version"); v != "" {
+		version, _ = strconv.Atoi(v)
+	}
+	version, ok := expectedVersion(c, version)
+	if !ok {
+		return
+	}
+
+	if err := h.store.RemoveFile(fileID, version); err != nil {
+		if errors.Is(err, storage.ErrVersionMismatch) {
+			h.respondVersionConflict(c, fileID, err)
+			return
+		}
 		c.JSON(http.StatusNotFound, gin.H{
 			"status":  "error",
 			"message": err.Error(),
@@ -150,7 +499,7 @@ func (h *FileHandler) RemoveFile(c *gin.Context) {
 }
 
 func (h *FileHandler) GetAllFiles(c *gin.Context) {
-	files := h.store.GetAllFiles()
+	files := filterByTags(h.store.GetAllFiles(), tagsFromQuery(c))
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "success",
 		"data":    files,
@@ -197,6 +546,7 @@ func (h *FileHandler) GetFile(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", versionETag(file.Version))
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "success",
 		"data":    file,
@@ -217,6 +567,8 @@ func (h *FileHandler) RenameFile(c *gin.Context) {
 
 	var req struct {
 		NewName string `json:"new_name" binding:"required"`
+		// 没有 If-Match 请求头时使用
+		Version int `json:"version"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -227,10 +579,19 @@ func (h *FileHandler) RenameFile(c *gin.Context) {
 		return
 	}
 
+	version, ok := expectedVersion(c, req.Version)
+	if !ok {
+		return
+	}
+
 	log.Info("✏️ 收到重命名请求: %s → %s", fileID, req.NewName)
 
-	if err := h.store.RenameFile(fileID, req.NewName); err != nil {
+	if err := h.store.RenameFile(fileID, req.NewName, version); err != nil {
 		log.Error("❌ 重命名失败: %v", err)
+		if errors.Is(err, storage.ErrVersionMismatch) {
+			h.respondVersionConflict(c, fileID, err)
+			return
+		}
 		c.JSON(http.StatusNotFound, gin.H{
 			"status":  "error",
 			"message": err.Error(),
@@ -240,6 +601,7 @@ func (h *FileHandler) RenameFile(c *gin.Context) {
 
 	file, _ := h.store.GetFile(fileID)
 	log.Info("✅ 重命名成功: %s (新名称: %s)", fileID, req.NewName)
+	c.Header("ETag", versionETag(file.Version))
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "success",
 		"data":    file,
@@ -258,24 +620,62 @@ func (h *FileHandler) GetFileContent(c *gin.Context) {
 		return
 	}
 
+	// offset/length 按字节读取，start_line/lines 按行读取
+	var r storage.ContentRange
+	for _, p := range []struct {
+		name string
+		dst  *int64
+	}{{"offset", &r.Offset}, {"length", &r.Length}} {
+		if v := c.Query(p.name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"status":  "error",
+					"message": "参数错误: " + p.name + " 必须是非负整数",
+				})
+				return
+			}
+			*p.dst = n
+		}
+	}
+	for _, p := range []struct {
+		name string
+		dst  *int
+	}{{"start_line", &r.StartLine}, {"lines", &r.Lines}} {
+		if v := c.Query(p.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"status":  "error",
+					"message": "参数错误: " + p.name + " 必须是非负整数",
+				})
+				return
+			}
+			*p.dst = n
+		}
+	}
+
 	log.Info("📖 获取文件内容: %s", fileID)
 
-	content, err := h.store.GetFileContent(fileID)
+	content, err := h.store.GetFileContent(fileID, r)
 	if err != nil {
 		log.Error("❌ 获取内容失败: %v", err)
-		c.JSON(http.StatusNotFound, gin.H{
+		status := http.StatusNotFound
+		if errors.Is(err, storage.ErrBinaryFile) {
+			status = http.StatusUnsupportedMediaType
+		}
+		c.JSON(status, gin.H{
 			"status":  "error",
 			"message": err.Error(),
 		})
 		return
 	}
 
-	log.Info("✅ 获取内容成功: %s", fileID)
+	log.Info("✅ 获取内容成功: %s (%d/%d bytes)", fileID, content.Length, content.TotalSize)
+	c.Header("ETag", versionETag(content.Version))
 	c.JSON(http.StatusOK, gin.H{
-		"status": "success",
-		"data": gin.H{
-			"content": content,
-		},
+		"status":  "success",
+		"data":    content,
 		"message": "获取内容成功",
 	})
 }
@@ -293,6 +693,10 @@ func (h *FileHandler) UpdateFileContent(c *gin.Context) {
 
 	var req struct {
 		Content string `json:"content"`
+		// 写回使用的编码，为空时保持文件原来的编码
+		Encoding string `json:"encoding"`
+		// 没有 If-Match 请求头时使用
+		Version int `json:"version"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -303,11 +707,32 @@ func (h *FileHandler) UpdateFileContent(c *gin.Context) {
 		return
 	}
 
+	version, ok := expectedVersion(c, req.Version)
+	if !ok {
+		return
+	}
+
 	log.Info("📝 收到更新文件内容请求: %s", fileID)
 
-	if err := h.store.UpdateFileContent(fileID, req.Content); err != nil {
+	if err := h.store.UpdateFileContent(fileID, req.Content, req.Encoding, version, lockToken(c)); err != nil {
 		log.Error("❌ 更新文件内容失败: %v", err)
-		c.JSON(http.StatusNotFound, gin.H{
+		if errors.Is(err, storage.ErrVersionMismatch) {
+			h.respondVersionConflict(c, fileID, err)
+			return
+		}
+		if errors.Is(err, storage.ErrFileLocked) {
+			c.JSON(http.StatusLocked, gin.H{
+				"status":  "error",
+				"data":    h.store.GetEditLock(fileID),
+				"message": err.Error(),
+			})
+			return
+		}
+		status := http.StatusNotFound
+		if _, exists := h.store.GetFile(fileID); exists {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
 			"status":  "error",
 			"message": err.Error(),
 		})
@@ -316,6 +741,7 @@ func (h *FileHandler) UpdateFileContent(c *gin.Context) {
 
 	file, _ := h.store.GetFile(fileID)
 	log.Info("✅ 更新文件内容成功: %s (ID: %s, 点击数: %d)", file.Name, fileID, file.Clicks)
+	c.Header("ETag", versionETag(file.Version))
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "success",
 		"data":    file,
@@ -353,10 +779,7 @@ func (h *FileHandler) CreateFile(c *gin.Context) {
 	file, err := h.store.CreateFile(req.Name, req.Content)
 	if err != nil {
 		log.Error("❌ 创建文件失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  "error",
-			"message": "创建文件失败: " + err.Error(),
-		})
+		respondUploadError(c, failedUpload(req.Name, "创建文件失败: ", err))
 		return
 	}
 
@@ -369,9 +792,13 @@ func (h *FileHandler) CreateFile(c *gin.Context) {
 }
 
 func (h *FileHandler) BulkClick(c *gin.Context) {
+	log := logger.GetInstance()
+
 	var req struct {
-		FileID string `json:"file_id" binding:"required"`
-		Count  int    `json:"count" binding:"required,min=1,max=1000"`
+		Clicks map[string]int `json:"clicks"`
+		// 兼容单文件格式
+		FileID string `json:"file_id"`
+		Count  int    `json:"count"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -382,23 +809,64 @@ func (h *FileHandler) BulkClick(c *gin.Context) {
 		return
 	}
 
-	for i := 0; i < req.Count; i++ {
-		if err := h.store.IncrementClick(req.FileID); err != nil {
-			c.JSON(http.StatusNotFound, gin.H{
-				"status":  "error",
-				"message": err.Error(),
-			})
-			return
+	if req.Clicks == nil {
+		req.Clicks = make(map[string]int)
+	}
+	if req.FileID != "" {
+		req.Clicks[req.FileID] += req.Count
+	}
+
+	if len(req.Clicks) == 0 || len(req.Clicks) > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "文件数量必须在1到1000之间",
+		})
+		return
+	}
+
+	// 单个文件的点击次数限制
+	errs := make(map[string]string)
+	for id, count := range req.Clicks {
+		if count < 1 || count > 1000 {
+			errs[id] = "点击次数必须在1到1000之间"
 		}
 	}
+	if len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "批量点击参数错误",
+			"errors":  errs,
+		})
+		return
+	}
+
+	results, err := h.store.ApplyClicks(req.Clicks)
+	if err != nil {
+		log.Error("❌ 批量点击失败: %v", err)
+		resp := gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		}
+		if bulkErr, ok := err.(*storage.BulkClickError); ok {
+			resp["errors"] = bulkErr.Errors
+		}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	added := 0
+	events := make([]storage.ClickEvent, 0, len(results))
+	for _, r := range results {
+		added += r.Added
+		events = append(events, newClickEvent(c, r.FileID, storage.SourceBulk, r.Added))
+	}
+	h.store.RecordClickEvents(events...)
 
-	file, _ := h.store.GetFile(req.FileID)
 	c.JSON(http.StatusOK, gin.H{
 		"status": "success",
 		"data": gin.H{
-			"file_id": req.FileID,
-			"clicks":  file.Clicks,
-			"added":   req.Count,
+			"results": results,
+			"added":   added,
 		},
 		"message": "批量点击成功",
 	})
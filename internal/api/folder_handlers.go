@@ -0,0 +1,245 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"file-ranking/internal/logger"
+	"file-ranking/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 路径参数中用 root 表示根目录
+const rootFolderParam = "root"
+
+func folderIDParam(c *gin.Context) string {
+	id := c.Param("id")
+	if id == rootFolderParam {
+		return storage.RootFolderID
+	}
+	return id
+}
+
+// 请求体中的文件夹ID同样接受 root
+func normalizeFolderID(id string) string {
+	if id == rootFolderParam {
+		return storage.RootFolderID
+	}
+	return id
+}
+
+func (h *FileHandler) ListFolders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    h.store.ListFolders(),
+		"message": "获取文件夹成功",
+	})
+}
+
+func (h *FileHandler) GetFolder(c *gin.Context) {
+	listing, err := h.store.ListFolder(folderIDParam(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    listing,
+		"message": "获取文件夹内容成功",
+	})
+}
+
+func (h *FileHandler) CreateFolder(c *gin.Context) {
+	log := logger.GetInstance()
+
+	var req struct {
+		Name     string `json:"name" binding:"required"`
+		ParentID string `json:"parent_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	folder, err := h.store.CreateFolder(req.Name, normalizeFolderID(req.ParentID))
+	if err != nil {
+		log.Error("❌ 创建文件夹失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"data":    folder,
+		"message": "文件夹创建成功",
+	})
+}
+
+func (h *FileHandler) RenameFolder(c *gin.Context) {
+	log := logger.GetInstance()
+	folderID := folderIDParam(c)
+
+	var req struct {
+		NewName string `json:"new_name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	folder, err := h.store.RenameFolder(folderID, req.NewName)
+	if err != nil {
+		log.Error("❌ 重命名文件夹失败: %v", err)
+		c.JSON(h.folderErrorStatus(folderID), gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    folder,
+		"message": "重命名成功",
+	})
+}
+
+func (h *FileHandler) MoveFolder(c *gin.Context) {
+	log := logger.GetInstance()
+	folderID := folderIDParam(c)
+
+	var req struct {
+		ParentID string `json:"parent_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	folder, err := h.store.MoveFolder(folderID, normalizeFolderID(req.ParentID))
+	if err != nil {
+		log.Error("❌ 移动文件夹失败: %v", err)
+		c.JSON(h.folderErrorStatus(folderID), gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    folder,
+		"message": "移动成功",
+	})
+}
+
+func (h *FileHandler) DeleteFolder(c *gin.Context) {
+	log := logger.GetInstance()
+	folderID := folderIDParam(c)
+	recursive := c.Query("recursive") == "true"
+
+	if err := h.store.DeleteFolder(folderID, recursive); err != nil {
+		log.Error("❌ 删除文件夹失败: %v", err)
+		status := h.folderErrorStatus(folderID)
+		if errors.Is(err, storage.ErrFileLocked) {
+			status = http.StatusLocked
+		} else if status == http.StatusBadRequest {
+			// 非空文件夹需要 recursive=true，子树中有文件正在保存时同样冲突
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "文件夹删除成功",
+	})
+}
+
+// GetFolderRanking 文件夹内的排行榜，默认包含子文件夹，recursive=false时只统计直接包含的文件
+func (h *FileHandler) GetFolderRanking(c *gin.Context) {
+	recursive := c.Query("recursive") != "false"
+	ranking, err := h.store.GetFolderRanking(folderIDParam(c), recursive)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    filterByTags(ranking, tagsFromQuery(c)),
+		"message": "获取排行榜成功",
+	})
+}
+
+func (h *FileHandler) MoveFile(c *gin.Context) {
+	log := logger.GetInstance()
+	fileID := c.Param("id")
+
+	var req struct {
+		FolderID string `json:"folder_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	file, err := h.store.MoveFile(fileID, normalizeFolderID(req.FolderID))
+	if err != nil {
+		log.Error("❌ 移动文件失败: %v", err)
+		status := http.StatusBadRequest
+		if _, exists := h.store.GetFile(fileID); !exists {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.Header("ETag", versionETag(file.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    file,
+		"message": "移动成功",
+	})
+}
+
+// 根目录不能修改；文件夹不存在返回404，其余为参数错误
+func (h *FileHandler) folderErrorStatus(folderID string) int {
+	if folderID == storage.RootFolderID {
+		return http.StatusBadRequest
+	}
+	if _, ok := h.store.GetFolder(folderID); !ok {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"file-ranking/internal/logger"
+	"file-ranking/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 获取或续期成功时返回锁和持有凭证，凭证只返回给持有者
+type heldLock struct {
+	*storage.EditLock
+	Token string `json:"token"`
+}
+
+// 编辑锁凭证，取 X-Lock-Token 请求头
+func lockToken(c *gin.Context) string {
+	return strings.TrimSpace(c.GetHeader("X-Lock-Token"))
+}
+
+func (h *FileHandler) ListLocks(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    h.store.ListEditLocks(),
+		"message": "获取编辑锁成功",
+	})
+}
+
+func (h *FileHandler) GetLock(c *gin.Context) {
+	fileID := c.Param("id")
+	if _, exists := h.store.GetFile(fileID); !exists {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "文件不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    h.store.GetEditLock(fileID),
+		"message": "获取编辑锁成功",
+	})
+}
+
+// AcquireLock 获取编辑锁，已持有时携带凭证再次获取相当于续期；
+// 文件正被其他人编辑时返回423和对方的锁
+func (h *FileHandler) AcquireLock(c *gin.Context) {
+	log := logger.GetInstance()
+	fileID := c.Param("id")
+
+	var req struct {
+		Holder string `json:"holder" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	lock, err := h.store.AcquireEditLock(fileID, req.Holder, lockToken(c))
+	if errors.Is(err, storage.ErrFileLocked) {
+		log.Warn("⚠️ 文件正在被编辑: %s (持有者: %s, 请求者: %s)", fileID, lock.Holder, req.Holder)
+		c.JSON(http.StatusLocked, gin.H{
+			"status":  "error",
+			"data":    lock,
+			"message": "文件正在被 " + lock.Holder + " 编辑",
+		})
+		return
+	}
+	if err != nil {
+		status := http.StatusBadRequest
+		if _, exists := h.store.GetFile(fileID); !exists {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    heldLock{EditLock: lock, Token: lock.Token},
+		"message": "获取编辑锁成功",
+	})
+}
+
+func (h *FileHandler) RenewLock(c *gin.Context) {
+	fileID := c.Param("id")
+	lock, err := h.store.RenewEditLock(fileID, lockToken(c))
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"status":  "error",
+			"data":    h.store.GetEditLock(fileID),
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    heldLock{EditLock: lock, Token: lock.Token},
+		"message": "编辑锁已续期",
+	})
+}
+
+func (h *FileHandler) ReleaseLock(c *gin.Context) {
+	fileID := c.Param("id")
+	if err := h.store.ReleaseEditLock(fileID, lockToken(c)); err != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"status":  "error",
+			"data":    h.store.GetEditLock(fileID),
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "编辑锁已释放",
+	})
+}
@@ -0,0 +1,220 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"file-ranking/internal/logger"
+	"file-ranking/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *FileHandler) GetMetadata(c *gin.Context) {
+	file, exists := h.store.GetFile(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "文件不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data": gin.H{
+			"id":          file.ID,
+			"tags":        nonNilTags(file.Tags),
+			"description": file.Description,
+			"metadata":    nonNilMetadata(file.Metadata),
+		},
+		"message": "获取元数据成功",
+	})
+}
+
+func (h *FileHandler) UpdateMetadata(c *gin.Context) {
+	log := logger.GetInstance()
+	fileID := c.Param("id")
+
+	var req storage.MetadataUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	file, err := h.store.UpdateMetadata(fileID, req)
+	if err != nil {
+		log.Error("❌ 更新元数据失败: %v", err)
+		status := http.StatusBadRequest
+		if _, exists := h.store.GetFile(fileID); !exists {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.Header("ETag", versionETag(file.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    file,
+		"message": "元数据更新成功",
+	})
+}
+
+func (h *FileHandler) GetTags(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    h.store.GetTags(),
+		"message": "获取标签成功",
+	})
+}
+
+// 从查询参数读取标签筛选条件，支持 tag=a&tag=b 和 tags=a,b
+func tagsFromQuery(c *gin.Context) []string {
+	tags := c.QueryArray("tag")
+	if v := c.Query("tags"); v != "" {
+		tags = append(tags, strings.Split(v, ",")...)
+	}
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+// 保留包含全部指定标签的文件，保持原有顺序
+func filterByTags(files []storage.FileData, tags []string) []storage.FileData {
+	if len(tags) == 0 {
+		return files
+	}
+	result := make([]storage.FileData, 0, len(files))
+	for i := range files {
+		if files[i].HasTags(tags) {
+			result = append(result, files[i])
+		}
+	}
+	return result
+}
+
+func nonNilTags(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+func nonNilMetadata(metadata map[string]string) map[string]string {
+	if metadata == nil {
+		return map[string]string{}
+	}
+	return metadata
+}
+
+// 解析到期时间：expiresAt 为RFC3339时间，expiresIn 为时长（如 72h、7d），都为空时不过期
+func parseExpiry(expiresAt, expiresIn string, now time.Time) (*time.Time, error) {
+	switch {
+	case expiresAt != "" && expiresIn != "":
+		return nil, fmt.Errorf("expires_at 和 expires_in 只能指定一个")
+	case expiresAt != "":
+		t, err := time.Parse(time.RFC3339, expiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("expires_at 格式错误，应为RFC3339时间")
+		}
+		if !t.After(now) {
+			return nil, fmt.Errorf("expires_at 必须晚于当前时间")
+		}
+		return &t, nil
+	case expiresIn != "":
+		var d time.Duration
+		if days, ok := strings.CutSuffix(expiresIn, "d"); ok {
+			n, err := strconv.Atoi(days)
+			if err != nil {
+				return nil, fmt.Errorf("expires_in 格式错误，如 72h 或 7d")
+			}
+			d = time.Duration(n) * 24 * time.Hour
+		} else {
+			var err error
+			if d, err = time.ParseDuration(expiresIn); err != nil {
+				return nil, fmt.Errorf("expires_in 格式错误，如 72h 或 7d")
+			}
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("expires_in 必须大于0")
+		}
+		t := now.Add(d)
+		return &t, nil
+	}
+	return nil, nil
+}
+
+// 上传时的到期时间，取表单字段或查询参数 expires_at / expires_in；格式错误时返回400
+func uploadExpiry(c *gin.Context) (*time.Time, bool) {
+	value := func(key string) string {
+		if v := c.PostForm(key); v != "" {
+			return v
+		}
+		return c.Query(key)
+	}
+	expiresAt, err := parseExpiry(value("expires_at"), value("expires_in"), time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return nil, false
+	}
+	return expiresAt, true
+}
+
+// SetExpiry 设置或清除文件的到期时间，到期后由清理任务按清理策略删除或归档
+func (h *FileHandler) SetExpiry(c *gin.Context) {
+	fileID := c.Param("id")
+
+	var req struct {
+		// 为空且 expires_in 也为空时清除到期时间
+		ExpiresAt string `json:"expires_at"`
+		ExpiresIn string `json:"expires_in"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+	expiresAt, err := parseExpiry(req.ExpiresAt, req.ExpiresIn, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	file, err := h.store.SetFileExpiry(fileID, expiresAt)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.Header("ETag", versionETag(file.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    file,
+		"message": "到期时间设置成功",
+	})
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"file-ranking/internal/logger"
+	"file-ranking/internal/preview"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PreviewFile 服务端渲染Markdown、CSV/TSV、JSON和YAML预览
+func (h *FileHandler) PreviewFile(c *gin.Context) {
+	log := logger.GetInstance()
+	file, exists := h.store.GetFile(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "文件不存在",
+		})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+
+	result, err := preview.Render(file, preview.Options{Page: page, PageSize: pageSize})
+	if err != nil {
+		status := http.StatusUnprocessableEntity
+		switch {
+		case errors.Is(err, preview.ErrUnsupported):
+			status = http.StatusUnsupportedMediaType
+		case errors.Is(err, preview.ErrTooLarge):
+			status = http.StatusRequestEntityTooLarge
+		default:
+			log.Error("❌ 预览失败: %s - %v", file.Name, err)
+		}
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    result,
+		"message": "预览成功",
+	})
+}
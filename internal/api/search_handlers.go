@@ -0,0 +1,126 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"file-ranking/internal/search"
+	"file-ranking/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SearchHandler struct {
+	store *storage.FileStore
+	index *search.Index
+	names *search.NameIndex
+}
+
+func NewSearchHandler(store *storage.FileStore, index *search.Index, names *search.NameIndex) *SearchHandler {
+	return &SearchHandler{
+		store: store,
+		index: index,
+		names: names,
+	}
+}
+
+// 搜索结果附带文件当前信息
+type searchHit struct {
+	search.Result
+	File storage.FileData `json:"file"`
+}
+
+func (h *SearchHandler) Search(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "搜索关键词不能为空",
+		})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	opts := search.Options{Limit: limit}
+	// boost=clicks 时热门文档排名靠前，按点击数的对数加权
+	if boost := c.Query("boost"); boost == "clicks" || boost == "true" {
+		opts.Boost = func(id string) float64 {
+			file, ok := h.store.GetFile(id)
+			if !ok {
+				return 1
+			}
+			return 1 + 0.2*math.Log1p(float64(file.Clicks))
+		}
+	}
+
+	results := h.index.Search(query, opts)
+	hits := make([]searchHit, 0, len(results))
+	for _, r := range results {
+		file, ok := h.store.GetFile(r.ID)
+		if !ok {
+			continue
+		}
+		hits = append(hits, searchHit{Result: r, File: *file})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    hits,
+		"message": "搜索成功",
+	})
+}
+
+// 文件名查找结果附带文件当前信息
+type nameHit struct {
+	search.NameMatch
+	File storage.FileData `json:"file"`
+}
+
+func (h *SearchHandler) LookupNames(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "查询内容不能为空",
+		})
+		return
+	}
+
+	mode := c.DefaultQuery("mode", search.LookupAll)
+	switch mode {
+	case search.LookupAll, search.LookupPrefix, search.LookupFuzzy, search.LookupPinyin:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "mode 只支持 all、prefix、fuzzy、pinyin",
+		})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit <= 0 || limit > 100 {
+		limit = 10
+	}
+
+	matches := h.names.Lookup(query, mode, limit)
+	hits := make([]nameHit, 0, len(matches))
+	for _, m := range matches {
+		file, ok := h.store.GetFile(m.ID)
+		if !ok {
+			continue
+		}
+		hits = append(hits, nameHit{NameMatch: m, File: *file})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    hits,
+		"message": "查找成功",
+	})
+}
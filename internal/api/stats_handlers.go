@@ -0,0 +1,98 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"file-ranking/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 各粒度未指定from时的默认查询范围
+var defaultSeriesRange = map[string]time.Duration{
+	storage.GranularityMinute: time.Hour,
+	storage.GranularityHour:   24 * time.Hour,
+	storage.GranularityDay:    30 * 24 * time.Hour,
+}
+
+func (h *FileHandler) GetStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    h.store.GetStats(),
+		"message": "获取统计信息成功",
+	})
+}
+
+func (h *FileHandler) GetFileStats(c *gin.Context) {
+	fileID := c.Param("id")
+	granularity := c.DefaultQuery("granularity", storage.GranularityHour)
+	if !storage.ValidGranularity(granularity) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "granularity 只支持 minute、hour、day",
+		})
+		return
+	}
+
+	to := time.Now()
+	if v := c.Query("to"); v != "" {
+		t, err := parseTimeParam(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": "参数错误: " + err.Error(),
+			})
+			return
+		}
+		to = t
+	}
+
+	from := to.Add(-defaultSeriesRange[granularity])
+	if v := c.Query("from"); v != "" {
+		t, err := parseTimeParam(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": "参数错误: " + err.Error(),
+			})
+			return
+		}
+		from = t
+	}
+
+	series, err := h.store.GetClickSeries(fileID, from, to, granularity)
+	if err != nil {
+		status := http.StatusBadRequest
+		if _, exists := h.store.GetFile(fileID); !exists {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    series,
+		"message": "获取点击统计成功",
+	})
+}
+
+// 支持RFC3339、日期(2006-01-02)和Unix秒
+func parseTimeParam(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", v, time.Local); err == nil {
+		return t, nil
+	}
+	if sec, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("无法解析时间: %s", v)
+}
@@ -0,0 +1,181 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"file-ranking/internal/logger"
+	"file-ranking/internal/storage"
+	"file-ranking/internal/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 单个分片请求体的大小上限
+const maxChunkSize = 32 * 1024 * 1024
+
+// CreateUploadSession 创建分片上传会话，之后用 PATCH 按偏移上传分片
+func (h *FileHandler) CreateUploadSession(c *gin.Context) {
+	log := logger.GetInstance()
+
+	var req struct {
+		Name     string `json:"name" binding:"required"`
+		Size     int64  `json:"size" binding:"required"`
+		FolderID string `json:"folder_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	session, err := h.store.CreateUploadSession(req.Name, req.Size, normalizeFolderID(req.FolderID))
+	if err != nil {
+		log.Error("❌ 创建上传会话失败: %v", err)
+		status, resp := http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		}
+		var verr *validation.Error
+		if errors.As(err, &verr) {
+			status = http.StatusUnprocessableEntity
+			resp["data"] = gin.H{"reasons": verr.Reasons}
+		}
+		c.JSON(status, resp)
+		return
+	}
+
+	c.Header("Location", "/api/uploads/"+session.ID)
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"data":    session,
+		"message": "上传会话创建成功",
+	})
+}
+
+// GetUploadSession 查询已接收的字节数，断点续传时从 received 处继续
+func (h *FileHandler) GetUploadSession(c *gin.Context) {
+	session, err := h.store.GetUploadSession(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(session.Received, 10))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    session,
+		"message": "获取上传进度成功",
+	})
+}
+
+// UploadChunk 请求体为原始字节，偏移由 Upload-Offset 头或 offset 参数指定
+func (h *FileHandler) UploadChunk(c *gin.Context) {
+	log := logger.GetInstance()
+	uploadID := c.Param("id")
+
+	offsetStr := c.GetHeader("Upload-Offset")
+	if offsetStr == "" {
+		offsetStr = c.Query("offset")
+	}
+	offset, err := strconv.ParseInt(offsetStr, 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "缺少或无效的分片偏移",
+		})
+		return
+	}
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxChunkSize)
+	session, err := h.store.WriteUploadChunk(uploadID, offset, body)
+	if session != nil {
+		c.Header("Upload-Offset", strconv.FormatInt(session.Received, 10))
+	}
+	if err != nil {
+		var mismatch *storage.OffsetMismatchError
+		var tooLarge *http.MaxBytesError
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, storage.ErrUploadSessionNotFound):
+			status = http.StatusNotFound
+		case errors.As(err, &mismatch):
+			status = http.StatusConflict
+		case errors.As(err, &tooLarge):
+			status = http.StatusRequestEntityTooLarge
+		case session == nil:
+			status = http.StatusInternalServerError
+		}
+		log.Error("❌ 写入分片失败: %s - %v", uploadID, err)
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"data":    session,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"data":    session,
+		"message": "分片上传成功",
+	})
+}
+
+// FinalizeUpload 数据全部到达后登记为文件，on_duplicate 指定内容与已有文件相同时的处理方式
+func (h *FileHandler) FinalizeUpload(c *gin.Context) {
+	log := logger.GetInstance()
+	uploadID := c.Param("id")
+	policy, ok := duplicatePolicy(c)
+	if !ok {
+		return
+	}
+	expiresAt, ok := uploadExpiry(c)
+	if !ok {
+		return
+	}
+
+	outcome, err := h.store.FinalizeUpload(uploadID, policy)
+	if err != nil {
+		log.Error("❌ 完成上传失败: %s - %v", uploadID, err)
+		status, resp := http.StatusConflict, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		}
+		var verr *validation.Error
+		switch {
+		case errors.Is(err, storage.ErrUploadSessionNotFound):
+			status = http.StatusNotFound
+		case errors.As(err, &verr):
+			status = http.StatusUnprocessableEntity
+			resp["data"] = gin.H{"reasons": verr.Reasons}
+		}
+		c.JSON(status, resp)
+		return
+	}
+
+	result := succeededUpload(outcome.File.Name, outcome)
+	h.applyExpiry(&result, expiresAt)
+	respondUploaded(c, result)
+}
+
+func (h *FileHandler) AbortUpload(c *gin.Context) {
+	if err := h.store.AbortUpload(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "上传已取消",
+	})
+}
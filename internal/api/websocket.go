@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"file-ranking/internal/logger"
+	"file-ranking/internal/storage"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
@@ -28,6 +29,15 @@ type WebSocketHub struct {
 	register   chan *websocket.Conn
 	unregister chan *websocket.Conn
 	mu         sync.RWMutex
+
+	// 客户端发送的点击消息交给该函数处理
+	onClick func(ev storage.ClickEvent) error
+}
+
+// 客户端发来的消息
+type clientMessage struct {
+	Type   string `json:"type"`
+	FileID string `json:"file_id"`
 }
 
 func NewWebSocketHub() *WebSocketHub {
@@ -73,6 +83,11 @@ func (h *WebSocketHub) Run() {
 	}
 }
 
+// SetClickHandler 设置WebSocket点击消息的处理函数
+func (h *WebSocketHub) SetClickHandler(fn func(ev storage.ClickEvent) error) {
+	h.onClick = fn
+}
+
 func (h *WebSocketHub) HandleWebSocket(c *gin.Context) {
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -80,6 +95,9 @@ func (h *WebSocketHub) HandleWebSocket(c *gin.Context) {
 		return
 	}
 
+	// 连接建立时记录点击元数据，gin.Context在处理函数返回后会被复用
+	meta := newClickEvent(c, "", storage.SourceWebSocket, 0)
+
 	h.register <- conn
 
 	go func() {
@@ -95,10 +113,11 @@ func (h *WebSocketHub) HandleWebSocket(c *gin.Context) {
 			})
 
 		for {
-			_, _, err := conn.ReadMessage()
+			_, data, err := conn.ReadMessage()
 			if err != nil {
 				break
 			}
+			h.handleMessage(data, meta)
 		}
 	}()
 }
@@ -115,4 +134,42 @@ func (h *WebSocketHub) BroadcastRanking(ranking interface{}) {
 	default:
 		// 丢弃消息避免阻塞
 	}
-}
\ No newline at end of file
+}
+// 编辑锁变化推送给所有客户端，lock为null表示锁已释放或过期
+type lockMessage struct {
+	Type   string            `json:"type"`
+	FileID string            `json:"file_id"`
+	Lock   *storage.EditLock `json:"lock"`
+}
+
+// WatchEditLocks 订阅编辑锁变化并实时推送，其他用户据此显示“正在编辑”
+func (h *WebSocketHub) WatchEditLocks(store *storage.FileStore) {
+	store.SubscribeLocks(func(ev storage.LockEvent) {
+		data, err := json.Marshal(lockMessage{Type: "lock", FileID: ev.FileID, Lock: ev.Lock})
+		if err != nil {
+			return
+		}
+		// 回调在获取、释放锁的请求中同步调用，推送队列满时丢弃消息而不阻塞请求；
+		// 客户端连接时重新获取全部编辑锁，并忽略已过期的锁
+		select {
+		case h.broadcast <- data:
+		default:
+			logger.GetInstance().Warn("⚠️ 推送队列已满，丢弃编辑锁消息: %s", ev.FileID)
+		}
+	})
+}
+
+func (h *WebSocketHub) handleMessage(data []byte, meta storage.ClickEvent) {
+	var msg clientMessage
+	if err := json.Unmarshal(data, &msg); err != nil || msg.Type != "click" || msg.FileID == "" {
+		return
+	}
+	if h.onClick == nil {
+		return
+	}
+
+	meta.FileID = msg.FileID
+	if err := h.onClick(meta); err != nil {
+		logger.GetInstance().Warn("⚠️ WebSocket点击失败: %v", err)
+	}
+}
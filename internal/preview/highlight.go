@@ -0,0 +1,167 @@
+package preview
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 高亮使用的CSS类
+const (
+	classKey     = "hl-key"
+	classString  = "hl-string"
+	classNumber  = "hl-number"
+	classLiteral = "hl-literal"
+	classComment = "hl-comment"
+)
+
+func span(class, text string) string {
+	return `<span class="` + class + `">` + html.EscapeString(text) + "</span>"
+}
+
+func renderJSON(data []byte) (*Result, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, bytes.TrimSpace(data), "", "  "); err != nil {
+		return nil, fmt.Errorf("解析JSON失败: %w", err)
+	}
+	return &Result{Kind: KindJSON, HTML: `<pre class="preview-code">` + highlightJSON(buf.String()) + "</pre>"}, nil
+}
+
+// 格式化后的JSON逐个扫描记号，字符串后紧跟冒号的是键
+func highlightJSON(src string) string {
+	var b strings.Builder
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '"':
+			j := i + 1
+			for j < len(src) && src[j] != '"' {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			j++
+			class := classString
+			if k := strings.IndexFunc(src[j:], func(r rune) bool { return r != ' ' }); k >= 0 && src[j+k] == ':' {
+				class = classKey
+			}
+			b.WriteString(span(class, src[i:j]))
+			i = j
+		case c == '-' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(src) && strings.IndexByte("0123456789.eE+-", src[j]) >= 0 {
+				j++
+			}
+			b.WriteString(span(classNumber, src[i:j]))
+			i = j
+		case c == 't' || c == 'f' || c == 'n':
+			j := i
+			for j < len(src) && src[j] >= 'a' && src[j] <= 'z' {
+				j++
+			}
+			b.WriteString(span(classLiteral, src[i:j]))
+			i = j
+		default:
+			b.WriteString(html.EscapeString(src[i : i+1]))
+			i++
+		}
+	}
+	return b.String()
+}
+
+func renderYAML(data []byte) (*Result, error) {
+	// 先按文档逐个解析再以统一缩进输出，保留注释
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	for {
+		var node yaml.Node
+		if err := dec.Decode(&node); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("解析YAML失败: %w", err)
+		}
+		if err := enc.Encode(&node); err != nil {
+			return nil, fmt.Errorf("格式化YAML失败: %w", err)
+		}
+	}
+	enc.Close()
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	// 多行文本块（| 或 >）内的行原样作为字符串，blockIndent为块所在键的缩进
+	blockIndent := -1
+	for i, line := range lines {
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if blockIndent >= 0 && (indent > blockIndent || strings.TrimSpace(line) == "") {
+			lines[i] = span(classString, line)
+			continue
+		}
+		blockIndent = -1
+		lines[i] = highlightYAMLLine(line)
+		if yamlBlockPattern.MatchString(line) {
+			blockIndent = indent
+		}
+	}
+	return &Result{Kind: KindYAML, HTML: `<pre class="preview-code">` + strings.Join(lines, "\n") + "</pre>"}, nil
+}
+
+var (
+	yamlKeyPattern    = regexp.MustCompile(`^(\s*(?:- )*)([^\s#'"][^:#]*?|"[^"]*"|'[^']*'):(\s|$)`)
+	yamlBlockPattern  = regexp.MustCompile(`[:-]\s+[|>][-+]?\d*\s*$`)
+	yamlNumberPattern = regexp.MustCompile(`^-?(?:\d+(?:\.\d*)?|\.\d+)(?:[eE][-+]?\d+)?$`)
+)
+
+func highlightYAMLLine(line string) string {
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "#") {
+		return span(classComment, line)
+	}
+
+	var b strings.Builder
+	rest := line
+	if m := yamlKeyPattern.FindStringSubmatchIndex(line); m != nil {
+		b.WriteString(html.EscapeString(line[:m[3]]))
+		b.WriteString(span(classKey, line[m[4]:m[5]]))
+		b.WriteString(":")
+		rest = line[m[5]+1:]
+	} else if indent := len(line) - len(strings.TrimLeft(line, " -")); indent > 0 {
+		b.WriteString(html.EscapeString(line[:indent]))
+		rest = line[indent:]
+	}
+
+	value, comment := rest, ""
+	if k := strings.Index(rest, " #"); k >= 0 && !strings.ContainsAny(rest[:k], `"'`) {
+		value, comment = rest[:k], rest[k:]
+	}
+
+	lead := value[:len(value)-len(strings.TrimLeft(value, " "))]
+	v := strings.TrimSpace(value)
+	b.WriteString(lead)
+	switch {
+	case v == "":
+	case strings.HasPrefix(v, `"`) || strings.HasPrefix(v, `'`):
+		b.WriteString(span(classString, v))
+	case yamlNumberPattern.MatchString(v):
+		b.WriteString(span(classNumber, v))
+	case v == "true" || v == "false" || v == "null" || v == "~":
+		b.WriteString(span(classLiteral, v))
+	case v == "|" || v == ">" || v == "|-" || v == ">-" || v == "{}" || v == "[]":
+		b.WriteString(html.EscapeString(v))
+	default:
+		b.WriteString(span(classString, v))
+	}
+	if comment != "" {
+		b.WriteString(span(classComment, comment))
+	}
+	return b.String()
+}
@@ -0,0 +1,24 @@
+package preview
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// goldmark默认不输出原始HTML，再经bluemonday清理一遍链接和属性
+var (
+	markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+	policy   = bluemonday.UGCPolicy()
+)
+
+func renderMarkdown(data []byte) (*Result, error) {
+	var buf bytes.Buffer
+	if err := markdown.Convert(data, &buf); err != nil {
+		return nil, fmt.Errorf("解析Markdown失败: %w", err)
+	}
+	return &Result{Kind: KindMarkdown, HTML: policy.Sanitize(buf.String())}, nil
+}
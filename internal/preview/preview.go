@@ -0,0 +1,101 @@
+package preview
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"file-ranking/internal/storage"
+)
+
+// 预览类型
+const (
+	KindMarkdown = "markdown"
+	KindTable    = "table"
+	KindJSON     = "json"
+	KindYAML     = "yaml"
+)
+
+// 整体读入内存渲染的文件大小上限，表格按页流式读取不受限制
+const maxRenderSize = 5 * 1024 * 1024
+
+// ErrUnsupported 文件类型不支持预览
+var ErrUnsupported = errors.New("该文件类型不支持预览")
+
+// ErrTooLarge 文件过大不能预览
+var ErrTooLarge = fmt.Errorf("文件超过 %dMB，不支持预览", maxRenderSize/1024/1024)
+
+// Options 预览参数，只对表格生效
+type Options struct {
+	Page     int
+	PageSize int
+}
+
+// Result 预览结果，HTML已做过清理或转义，可以直接插入页面
+type Result struct {
+	Kind  string     `json:"kind"`
+	HTML  string     `json:"html"`
+	Table *TablePage `json:"table,omitempty"`
+}
+
+// Detect 根据文件名和识别出的类型判断预览方式，不支持时返回空字符串
+func Detect(name, mimeType string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".md", ".markdown":
+		return KindMarkdown
+	case ".csv", ".tsv":
+		return KindTable
+	case ".json":
+		return KindJSON
+	case ".yaml", ".yml":
+		return KindYAML
+	}
+
+	switch {
+	case strings.HasPrefix(mimeType, "text/csv"), strings.HasPrefix(mimeType, "text/tab-separated-values"):
+		return KindTable
+	case strings.HasPrefix(mimeType, "application/json"):
+		return KindJSON
+	}
+	return ""
+}
+
+// Render 渲染文件预览，非UTF-8编码的文本先转换为UTF-8
+func Render(file *storage.FileData, opts Options) (*Result, error) {
+	name, mimeType := file.Name, file.MimeType
+	kind := Detect(name, mimeType)
+	if kind == "" {
+		return nil, ErrUnsupported
+	}
+
+	f, err := os.Open(file.Path)
+	if err != nil {
+		return nil, fmt.Errorf("打开文件失败: %w", err)
+	}
+	defer f.Close()
+	r := storage.NewTextReader(f, file.Encoding)
+
+	if kind == KindTable {
+		return renderTable(r, tableDelimiter(name, mimeType), opts)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, maxRenderSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取文件失败: %w", err)
+	}
+	if len(data) > maxRenderSize {
+		return nil, ErrTooLarge
+	}
+
+	switch kind {
+	case KindMarkdown:
+		return renderMarkdown(data)
+	case KindJSON:
+		return renderJSON(data)
+	default:
+		return renderYAML(data)
+	}
+}
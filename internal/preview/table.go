@@ -0,0 +1,108 @@
+package preview
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// 表格分页默认值和上限
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// TablePage 表格分页信息，第一行作为表头不计入行数
+type TablePage struct {
+	Page       int      `json:"page"`
+	PageSize   int      `json:"page_size"`
+	TotalRows  int      `json:"total_rows"`
+	TotalPages int      `json:"total_pages"`
+	Columns    []string `json:"columns"`
+}
+
+func tableDelimiter(name, mimeType string) rune {
+	if strings.EqualFold(filepath.Ext(name), ".tsv") || strings.HasPrefix(mimeType, "text/tab-separated-values") {
+		return '\t'
+	}
+	return ','
+}
+
+// 逐行读取，只保留当前页的数据，其余行只计数
+func renderTable(r io.Reader, delimiter rune, opts Options) (*Result, error) {
+	if opts.Page < 1 {
+		opts.Page = 1
+	}
+	if opts.PageSize <= 0 {
+		opts.PageSize = defaultPageSize
+	}
+	if opts.PageSize > maxPageSize {
+		opts.PageSize = maxPageSize
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.ReuseRecord = true
+
+	page := &TablePage{Page: opts.Page, PageSize: opts.PageSize, Columns: []string{}}
+	start := (opts.Page - 1) * opts.PageSize
+	rows := make([][]string, 0)
+
+	for line := 0; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析表格失败: %w", err)
+		}
+		if line == 0 {
+			page.Columns = append(page.Columns, record...)
+			continue
+		}
+		if page.TotalRows >= start && page.TotalRows < start+opts.PageSize {
+			rows = append(rows, append([]string(nil), record...))
+		}
+		page.TotalRows++
+	}
+	page.TotalPages = (page.TotalRows + opts.PageSize - 1) / opts.PageSize
+
+	width := len(page.Columns)
+	for _, row := range rows {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(`<table class="preview-table"><thead><tr>`)
+	for i := 0; i < width; i++ {
+		b.WriteString("<th>")
+		if i < len(page.Columns) {
+			b.WriteString(html.EscapeString(page.Columns[i]))
+		}
+		b.WriteString("</th>")
+	}
+	b.WriteString("</tr></thead><tbody>")
+	for _, row := range rows {
+		b.WriteString("<tr>")
+		// 列数不足的行补空单元格
+		for i := 0; i < width; i++ {
+			b.WriteString("<td>")
+			if i < len(row) {
+				b.WriteString(html.EscapeString(row[i]))
+			}
+			b.WriteString("</td>")
+		}
+		b.WriteString("</tr>")
+	}
+	b.WriteString("</tbody></table>")
+
+	return &Result{Kind: KindTable, HTML: b.String(), Table: page}, nil
+}
@@ -0,0 +1,246 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// BM25参数，文件名命中的权重高于正文
+const (
+	bm25K1     = 1.2
+	bm25B      = 0.75
+	nameWeight = 3.0
+	// 中日韩单字的权重低于二元组
+	unigramWeight = 0.3
+)
+
+// Document 被索引的文档
+type Document struct {
+	ID      string
+	Name    string
+	Content string
+}
+
+// Options 查询选项
+type Options struct {
+	Limit int
+	// Boost 返回文档的额外权重，分数乘以该值；为nil时不加权。调用时不持有索引锁
+	Boost func(id string) float64
+}
+
+// Result 一条搜索结果
+type Result struct {
+	ID            string  `json:"id"`
+	Name          string  `json:"name"`
+	Score         float64 `json:"score"`
+	NameHighlight string  `json:"name_highlight"`
+	Snippet       string  `json:"snippet"`
+}
+
+type posting struct {
+	name    int
+	content int
+}
+
+type docEntry struct {
+	doc    Document
+	length float64 // 加权后的文档长度
+	terms  []string
+}
+
+// Index 倒排索引，并发安全
+type Index struct {
+	mu       sync.RWMutex
+	docs     map[string]*docEntry
+	postings map[string]map[string]posting
+	totalLen float64
+}
+
+func NewIndex() *Index {
+	return &Index{
+		docs:     make(map[string]*docEntry),
+		postings: make(map[string]map[string]posting),
+	}
+}
+
+// Put 添加或替换文档
+func (idx *Index) Put(doc Document) {
+	nameTF := termFrequencies(indexTokens(doc.Name))
+	contentTF := termFrequencies(indexTokens(doc.Content))
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.removeLocked(doc.ID)
+
+	entry := &docEntry{doc: doc}
+	for term, n := range nameTF {
+		idx.addPosting(term, doc.ID, posting{name: n})
+		entry.length += nameWeight * float64(n) * termWeight(term)
+		entry.terms = append(entry.terms, term)
+	}
+	for term, n := range contentTF {
+		p := idx.postings[term][doc.ID]
+		if p.name == 0 {
+			entry.terms = append(entry.terms, term)
+		}
+		p.content = n
+		idx.addPosting(term, doc.ID, p)
+		entry.length += float64(n) * termWeight(term)
+	}
+
+	idx.docs[doc.ID] = entry
+	idx.totalLen += entry.length
+}
+
+func termWeight(term string) float64 {
+	if isUnigram(term) {
+		return unigramWeight
+	}
+	return 1
+}
+
+// Rename 只更新文件名，保留正文
+func (idx *Index) Rename(id, name string) {
+	idx.mu.RLock()
+	entry, ok := idx.docs[id]
+	idx.mu.RUnlock()
+	if !ok {
+		return
+	}
+	doc := entry.doc
+	doc.Name = name
+	idx.Put(doc)
+}
+
+// Remove 删除文档
+func (idx *Index) Remove(id string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.removeLocked(id)
+}
+
+// Len 已索引文档数
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.docs)
+}
+
+// IDs 所有已索引文档的ID
+func (idx *Index) IDs() []string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	ids := make([]string, 0, len(idx.docs))
+	for id := range idx.docs {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (idx *Index) addPosting(term, id string, p posting) {
+	docs, ok := idx.postings[term]
+	if !ok {
+		docs = make(map[string]posting)
+		idx.postings[term] = docs
+	}
+	docs[id] = p
+}
+
+func (idx *Index) removeLocked(id string) {
+	entry, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+	for _, term := range entry.terms {
+		docs := idx.postings[term]
+		delete(docs, id)
+		if len(docs) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	idx.totalLen -= entry.length
+	delete(idx.docs, id)
+}
+
+// Search 按BM25对文档排序，命中查询词比例越高分数越高
+func (idx *Index) Search(query string, opts Options) []Result {
+	tokens := Tokenize(query)
+	terms := uniqueTokens(tokens)
+	if len(terms) == 0 {
+		return []Result{}
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 20
+	}
+
+	idx.mu.RLock()
+	n := float64(len(idx.docs))
+	if n == 0 {
+		idx.mu.RUnlock()
+		return []Result{}
+	}
+	avgLen := idx.totalLen / n
+	if avgLen == 0 {
+		avgLen = 1
+	}
+
+	scores := make(map[string]float64)
+	matched := make(map[string]int)
+	for _, term := range terms {
+		docs := idx.postings[term]
+		if len(docs) == 0 {
+			continue
+		}
+		df := float64(len(docs))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for id, p := range docs {
+			tf := nameWeight*float64(p.name) + float64(p.content)
+			norm := 1 - bm25B + bm25B*idx.docs[id].length/avgLen
+			scores[id] += termWeight(term) * idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+			matched[id]++
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for id, score := range scores {
+		coverage := float64(matched[id]) / float64(len(terms))
+		results = append(results, Result{ID: id, Score: score * coverage * coverage})
+	}
+	idx.mu.RUnlock()
+
+	// Boost可能读取文件存储，不能在持有索引锁时调用，否则与持有存储锁等待索引的写入方死锁
+	if opts.Boost != nil {
+		for i := range results {
+			results[i].Score *= opts.Boost(results[i].ID)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+
+	// 释放锁期间可能有文档被删除，跳过后再截断
+	idx.mu.RLock()
+	kept := results[:0]
+	for _, r := range results {
+		if len(kept) == opts.Limit {
+			break
+		}
+		entry, ok := idx.docs[r.ID]
+		if !ok {
+			continue
+		}
+		r.Name = entry.doc.Name
+		r.NameHighlight = highlight(entry.doc.Name, terms, 0)
+		r.Snippet = highlight(entry.doc.Content, terms, snippetRadius)
+		kept = append(kept, r)
+	}
+	idx.mu.RUnlock()
+	results = kept
+
+	return results
+}
@@ -0,0 +1,85 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Hello World", []string{"hello", "world"}},
+		{"中文分词", []string{"中文", "文分", "分词"}},
+		{"中", []string{"中"}},
+		{"Go语言入门", []string{"go", "语言", "言入", "入门"}},
+	}
+	for _, tt := range tests {
+		if got := Tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %v, 期望 %v", tt.text, got, tt.want)
+		}
+	}
+
+	// 索引时额外保留单字
+	want := []string{"go", "语言", "语", "言"}
+	if got := indexTokens("Go语言"); !reflect.DeepEqual(got, want) {
+		t.Errorf("indexTokens = %v, 期望 %v", got, want)
+	}
+}
+
+func searchIDs(idx *Index, query string, opts Options) []string {
+	ids := []string{}
+	for _, r := range idx.Search(query, opts) {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func TestSearchCJK(t *testing.T) {
+	idx := NewIndex()
+	idx.Put(Document{ID: "a", Name: "学习笔记.txt", Content: "Go语言并发编程"})
+	idx.Put(Document{ID: "b", Name: "readme.md", Content: "语法说明"})
+	idx.Put(Document{ID: "c", Name: "list.txt", Content: "go modules"})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// 单字查询命中包含该字的多字片段
+		{"语", []string{"b", "a"}},
+		{"笔", []string{"a"}},
+		// 中英混合查询，两部分都命中的文档排在前面
+		{"go语言", []string{"a", "c"}},
+		{"go 并", []string{"a", "c"}},
+		{"语言", []string{"a"}},
+	}
+	for _, tt := range tests {
+		if got := searchIDs(idx, tt.query, Options{}); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %v, 期望 %v", tt.query, got, tt.want)
+		}
+	}
+
+	// 多字查询仍按二元组匹配，只包含相同单字的文档不命中
+	idx.Put(Document{ID: "d", Name: "d.txt", Content: "语文 言语"})
+	if got := searchIDs(idx, "语言", Options{}); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("Search(语言) = %v, 期望 [a]", got)
+	}
+}
+
+// Boost在索引锁之外调用，回调里修改索引不会死锁，期间删除的文档不返回
+func TestSearchBoostWithoutIndexLock(t *testing.T) {
+	idx := NewIndex()
+	idx.Put(Document{ID: "a", Name: "a.txt", Content: "report"})
+	idx.Put(Document{ID: "b", Name: "b.txt", Content: "report report"})
+
+	results := idx.Search("report", Options{Boost: func(id string) float64 {
+		if id == "b" {
+			idx.Remove("b")
+		}
+		return 1
+	}})
+	if len(results) != 1 || results[0].ID != "a" {
+		t.Fatalf("results = %+v, 期望只有 a", results)
+	}
+}
@@ -0,0 +1,109 @@
+package search
+
+import (
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"file-ranking/internal/logger"
+	"file-ranking/internal/storage"
+)
+
+// 单个文件最多索引的正文字节数
+const maxIndexBytes = 2 << 20
+
+// Indexer 订阅文件存储的变更，在后台按顺序更新索引
+type Indexer struct {
+	index *Index
+	store *storage.FileStore
+	queue chan storage.FileEvent
+
+	// 队列满时丢弃事件并置位，队列处理完后重建全量索引
+	overflow atomic.Bool
+}
+
+// NewIndexer 订阅变更并在后台建立全量索引
+func NewIndexer(index *Index, store *storage.FileStore) *Indexer {
+	ix := &Indexer{
+		index: index,
+		store: store,
+		queue: make(chan storage.FileEvent, 1000),
+	}
+	// 回调不能阻塞写入方，批量导入、打包等突发变更超出队列时改为全量重建
+	store.Subscribe(func(ev storage.FileEvent) {
+		select {
+		case ix.queue <- ev:
+		default:
+			ix.overflow.Store(true)
+		}
+	})
+	go ix.run(store.GetAllFiles())
+	return ix
+}
+
+func (ix *Indexer) run(initial []storage.FileData) {
+	log := logger.GetInstance()
+	for _, file := range initial {
+		ix.apply(storage.FileEvent{Type: storage.FileCreated, File: file})
+	}
+	log.Info("🔎 全文索引建立完成 - 文件数: %d", ix.index.Len())
+
+	for ev := range ix.queue {
+		ix.apply(ev)
+		if len(ix.queue) == 0 && ix.overflow.Swap(false) {
+			ix.rebuild()
+		}
+	}
+}
+
+// 有事件被丢弃时按存储的当前状态重建索引
+func (ix *Indexer) rebuild() {
+	files := ix.store.GetAllFiles()
+	current := make(map[string]bool, len(files))
+	for _, file := range files {
+		current[file.ID] = true
+		ix.apply(storage.FileEvent{Type: storage.FileCreated, File: file})
+	}
+	for _, id := range ix.index.IDs() {
+		if !current[id] {
+			ix.index.Remove(id)
+		}
+	}
+	logger.GetInstance().Warn("⚠️ 索引更新队列已满，已重建全量索引 - 文件数: %d", ix.index.Len())
+}
+
+func (ix *Indexer) apply(ev storage.FileEvent) {
+	switch ev.Type {
+	case storage.FileRemoved:
+		ix.index.Remove(ev.File.ID)
+	case storage.FileRenamed, storage.FileMetadataChanged:
+		ix.index.Rename(ev.File.ID, ev.File.Name)
+	default:
+		ix.index.Put(Document{
+			ID:      ev.File.ID,
+			Name:    ev.File.Name,
+			Content: readText(ev.File),
+		})
+	}
+}
+
+// 读取文件的文本内容并转换为UTF-8，二进制文件返回空字符串
+func readText(file storage.FileData) string {
+	if file.Encoding == "" {
+		return ""
+	}
+
+	f, err := os.Open(file.Path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(storage.NewTextReader(io.LimitReader(f, maxIndexBytes), file.Encoding))
+	if err != nil {
+		return ""
+	}
+	// 截断处可能切开多字节字符
+	return strings.ToValidUTF8(string(data), "")
+}
@@ -0,0 +1,301 @@
+package search
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"file-ranking/internal/storage"
+
+	"github.com/mozillazg/go-pinyin"
+)
+
+// 文件名查找模式
+const (
+	LookupAll    = "all"
+	LookupPrefix = "prefix"
+	LookupFuzzy  = "fuzzy"
+	LookupPinyin = "pinyin"
+)
+
+// 各匹配方式的基础分，越精确分数越高
+const (
+	scoreExact          = 100
+	scorePrefix         = 90
+	scorePinyinPrefix   = 80
+	scoreInitialsPrefix = 75
+	scoreSubstring      = 70
+	scorePinyinContains = 60
+	scoreInitials       = 55
+	scoreFuzzy          = 50
+)
+
+// NameMatch 一条文件名查找结果
+type NameMatch struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Score int    `json:"score"`
+	Match string `json:"match"`
+}
+
+// 预先计算的文件名各种形式，只保留字母和数字
+type nameEntry struct {
+	id       string
+	name     string
+	lower    string
+	base     string
+	pinyin   string
+	initials string
+	// 从每个词开始的后缀，容错匹配时允许从词首开始对齐
+	wordTails []string
+}
+
+// NameIndex 文件名索引，支持前缀、拼音、拼音首字母和容错匹配
+type NameIndex struct {
+	mu      sync.RWMutex
+	entries map[string]*nameEntry
+}
+
+// NewNameIndex 建立文件名索引并订阅文件变更保持同步
+func NewNameIndex(store *storage.FileStore) *NameIndex {
+	idx := &NameIndex{entries: make(map[string]*nameEntry)}
+	store.Subscribe(func(ev storage.FileEvent) {
+		if ev.Type == storage.FileRemoved {
+			idx.Remove(ev.File.ID)
+		} else {
+			idx.Put(ev.File.ID, ev.File.Name)
+		}
+	})
+	for _, file := range store.GetAllFiles() {
+		idx.Put(file.ID, file.Name)
+	}
+	return idx
+}
+
+// Put 添加或更新文件名
+func (idx *NameIndex) Put(id, name string) {
+	entry := newNameEntry(id, name)
+	idx.mu.Lock()
+	idx.entries[id] = entry
+	idx.mu.Unlock()
+}
+
+// Remove 删除文件名
+func (idx *NameIndex) Remove(id string) {
+	idx.mu.Lock()
+	delete(idx.entries, id)
+	idx.mu.Unlock()
+}
+
+var pinyinArgs = pinyin.Args{
+	Style: pinyin.Normal,
+	// 非汉字原样保留
+	Fallback: func(r rune, a pinyin.Args) []string {
+		return []string{string(r)}
+	},
+}
+
+func newNameEntry(id, name string) *nameEntry {
+	entry := &nameEntry{
+		id:    id,
+		name:  name,
+		lower: normalizeName(name),
+		base:  normalizeName(strings.TrimSuffix(name, filepath.Ext(name))),
+	}
+
+	var full, initials strings.Builder
+	for _, syllables := range pinyin.Pinyin(name, pinyinArgs) {
+		if len(syllables) == 0 {
+			continue
+		}
+		s := normalizeName(syllables[0])
+		if s == "" {
+			continue
+		}
+		full.WriteString(s)
+		// 汉字取拼音首字母，其他字符原样保留
+		if len([]rune(syllables[0])) > 1 && isASCIIWord(syllables[0]) {
+			initials.WriteByte(s[0])
+		} else {
+			initials.WriteString(s)
+		}
+	}
+	entry.pinyin = full.String()
+	entry.initials = initials.String()
+
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i := 1; i < len(words); i++ {
+		entry.wordTails = append(entry.wordTails, normalizeName(strings.Join(words[i:], "")))
+	}
+	return entry
+}
+
+func isASCIIWord(s string) bool {
+	for _, r := range s {
+		if r > unicode.MaxASCII || !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// 转小写并去掉空白和标点
+func normalizeName(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
+
+// Lookup 按模式查找文件名，结果按分数降序
+func (idx *NameIndex) Lookup(query, mode string, limit int) []NameMatch {
+	q := normalizeName(query)
+	if q == "" {
+		return []NameMatch{}
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+
+	idx.mu.RLock()
+	results := make([]NameMatch, 0)
+	for _, entry := range idx.entries {
+		score, match := entry.match(q, mode)
+		if score > 0 {
+			results = append(results, NameMatch{ID: entry.id, Name: entry.name, Score: score, Match: match})
+		}
+	}
+	idx.mu.RUnlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		// 同分时名字短的更接近查询
+		if len(results[i].Name) != len(results[j].Name) {
+			return len(results[i].Name) < len(results[j].Name)
+		}
+		return results[i].ID < results[j].ID
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+func (e *nameEntry) match(q, mode string) (int, string) {
+	all := mode == LookupAll || mode == ""
+
+	if all || mode == LookupPrefix {
+		switch {
+		case e.lower == q || e.base == q:
+			return scoreExact, "exact"
+		case strings.HasPrefix(e.lower, q):
+			return scorePrefix, "prefix"
+		}
+		if mode == LookupPrefix {
+			if strings.HasPrefix(e.pinyin, q) {
+				return scorePinyinPrefix, "pinyin"
+			}
+			if strings.HasPrefix(e.initials, q) {
+				return scoreInitialsPrefix, "initials"
+			}
+			return 0, ""
+		}
+	}
+
+	if all || mode == LookupPinyin {
+		switch {
+		case strings.HasPrefix(e.pinyin, q):
+			return scorePinyinPrefix, "pinyin"
+		case strings.HasPrefix(e.initials, q):
+			return scoreInitialsPrefix, "initials"
+		}
+	}
+
+	if all && strings.Contains(e.lower, q) {
+		return scoreSubstring, "substring"
+	}
+
+	if all || mode == LookupPinyin {
+		switch {
+		case strings.Contains(e.pinyin, q):
+			return scorePinyinContains, "pinyin"
+		case strings.Contains(e.initials, q):
+			return scoreInitials, "initials"
+		}
+	}
+
+	if all || mode == LookupFuzzy {
+		maxDist := allowedTypos(q)
+		if maxDist == 0 {
+			return 0, ""
+		}
+		best := -1
+		candidates := append([]string{e.lower, e.pinyin}, e.wordTails...)
+		for _, candidate := range candidates {
+			if d := prefixDistance(q, candidate); d <= maxDist && (best < 0 || d < best) {
+				best = d
+			}
+		}
+		if best >= 0 {
+			return scoreFuzzy - 10*best, "fuzzy"
+		}
+	}
+	return 0, ""
+}
+
+// 查询越长允许的错误越多，过短的查询不做容错
+func allowedTypos(q string) int {
+	n := len([]rune(q))
+	switch {
+	case n < 3:
+		return 0
+	case n <= 5:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// 查询与候选任意前缀之间的最小编辑距离（含相邻字符交换）
+func prefixDistance(q, candidate string) int {
+	a := []rune(q)
+	b := []rune(candidate)
+
+	// d[i][j]: a[:i] 与 b[:j] 的编辑距离
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := 0; j <= len(b); j++ {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	best := d[len(a)][0]
+	for j := 1; j <= len(b); j++ {
+		best = min(best, d[len(a)][j])
+	}
+	return best
+}
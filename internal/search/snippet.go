@@ -0,0 +1,128 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 摘要中命中位置前后保留的字符数
+const snippetRadius = 60
+
+// 在文本中用<mark>标出查询词，radius>0时只截取首个命中附近的片段，结果已做HTML转义
+func highlight(text string, terms []string, radius int) string {
+	needles := make([][]rune, 0, len(terms))
+	for _, t := range terms {
+		needles = append(needles, []rune(t))
+	}
+
+	// 正文最大2MB，先在原文中定位首个命中并截出前后radius个字符，只转换这一段
+	cutStart, cutEnd := false, false
+	if radius > 0 {
+		first := -1
+		for _, n := range needles {
+			if i := indexFold(text, n); i >= 0 && (first < 0 || i < first) {
+				first = i
+			}
+		}
+		if first < 0 {
+			first = 0
+		}
+		start, end := first, first
+		for k := 0; k < radius && start > 0; k++ {
+			_, size := utf8.DecodeLastRuneInString(text[:start])
+			start -= size
+		}
+		for k := 0; k < radius && end < len(text); k++ {
+			_, size := utf8.DecodeRuneInString(text[end:])
+			end += size
+		}
+		cutStart, cutEnd = start > 0, end < len(text)
+		text = text[start:end]
+	}
+
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		// 摘要中的换行、制表符统一为空格
+		if radius > 0 && unicode.IsSpace(r) {
+			runes[i] = ' '
+		}
+		lower[i] = unicode.ToLower(runes[i])
+	}
+
+	marks := make([]bool, len(runes))
+	for _, n := range needles {
+		for i := indexRunes(lower, n, 0); i >= 0; i = indexRunes(lower, n, i+1) {
+			for j := i; j < i+len(n); j++ {
+				marks[j] = true
+			}
+		}
+	}
+
+	var b strings.Builder
+	if cutStart {
+		b.WriteString("…")
+	}
+	for i := 0; i < len(marks); {
+		j := i
+		for j < len(marks) && marks[j] == marks[i] {
+			j++
+		}
+		part := string(runes[i:j])
+		if marks[i] {
+			b.WriteString("<mark>" + html.EscapeString(part) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(part))
+		}
+		i = j
+	}
+	if cutEnd {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// 不区分大小写查找needle在s中首次出现的字节偏移，不转换整个字符串
+func indexFold(s string, needle []rune) int {
+	if len(needle) == 0 {
+		return -1
+	}
+	for i := 0; i < len(s); {
+		j, k := i, 0
+		for k < len(needle) && j < len(s) {
+			r, size := utf8.DecodeRuneInString(s[j:])
+			if unicode.ToLower(r) != needle[k] {
+				break
+			}
+			j += size
+			k++
+		}
+		if k == len(needle) {
+			return i
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	return -1
+}
+
+func indexRunes(s, sub []rune, from int) int {
+	if len(sub) == 0 {
+		return -1
+	}
+	for i := from; i+len(sub) <= len(s); i++ {
+		match := true
+		for j := range sub {
+			if s[i+j] != sub[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,134 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 连续同类字符组成的片段
+type segment struct {
+	text  []rune
+	start int // 在原文中的rune偏移
+	cjk   bool
+}
+
+// 中日韩文字按字切分，需要二元分词
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// 将文本切分为拉丁词片段和中日韩片段，字母统一转小写
+func segments(text string) []segment {
+	var (
+		result []segment
+		cur    []rune
+		start  int
+		curCJK bool
+	)
+	flush := func() {
+		if len(cur) > 0 {
+			result = append(result, segment{text: cur, start: start, cjk: curCJK})
+			cur = nil
+		}
+	}
+
+	i := 0
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			if len(cur) > 0 && !curCJK {
+				flush()
+			}
+			if len(cur) == 0 {
+				start, curCJK = i, true
+			}
+			cur = append(cur, r)
+		case isWordRune(r):
+			if len(cur) > 0 && curCJK {
+				flush()
+			}
+			if len(cur) == 0 {
+				start, curCJK = i, false
+			}
+			cur = append(cur, unicode.ToLower(r))
+		default:
+			flush()
+		}
+		i++
+	}
+	flush()
+	return result
+}
+
+// Tokenize 拉丁文字按词切分，中日韩文字切分为相邻二元组，单字片段保留单字
+func Tokenize(text string) []string {
+	var tokens []string
+	for _, seg := range segments(text) {
+		tokens = append(tokens, segmentTokens(seg)...)
+	}
+	return tokens
+}
+
+func segmentTokens(seg segment) []string {
+	if !seg.cjk {
+		return []string{string(seg.text)}
+	}
+	if len(seg.text) == 1 {
+		return []string{string(seg.text)}
+	}
+	tokens := make([]string, 0, len(seg.text)-1)
+	for i := 0; i+1 < len(seg.text); i++ {
+		tokens = append(tokens, string(seg.text[i:i+2]))
+	}
+	return tokens
+}
+
+// 建立索引时中日韩片段额外保留单字，单字查询也能命中多字片段
+func indexTokens(text string) []string {
+	var tokens []string
+	for _, seg := range segments(text) {
+		tokens = append(tokens, segmentTokens(seg)...)
+		if seg.cjk && len(seg.text) > 1 {
+			for _, r := range seg.text {
+				tokens = append(tokens, string(r))
+			}
+		}
+	}
+	return tokens
+}
+
+// 中日韩单字词，区分度低于二元组
+func isUnigram(term string) bool {
+	r, size := utf8.DecodeRuneInString(term)
+	return size == len(term) && isCJK(r)
+}
+
+// 统计词频
+func termFrequencies(tokens []string) map[string]int {
+	tf := make(map[string]int, len(tokens))
+	for _, t := range tokens {
+		tf[t]++
+	}
+	return tf
+}
+
+// 去重并保持顺序
+func uniqueTokens(tokens []string) []string {
+	seen := make(map[string]bool, len(tokens))
+	result := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		if t = strings.TrimSpace(t); t != "" && !seen[t] {
+			seen[t] = true
+			result = append(result, t)
+		}
+	}
+	return result
+}
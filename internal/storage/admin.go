@@ -0,0 +1,247 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"file-ranking/internal/logger"
+)
+
+// AuditEntry 点击数修改的审计记录
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	Action    string    `json:"action"`
+	FileID    string    `json:"file_id"`
+	Name      string    `json:"name"`
+	OldClicks int       `json:"old_clicks"`
+	NewClicks int       `json:"new_clicks"`
+	Reason    string    `json:"reason"`
+	Actor     string    `json:"actor,omitempty"`
+}
+
+// FileFilter 按条件筛选文件，所有非空条件同时满足才算匹配
+type FileFilter struct {
+	IDs            []string   `json:"ids,omitempty"`
+	NameContains   string     `json:"name_contains,omitempty"`
+	Tags           []string   `json:"tags,omitempty"`
+	MinClicks      *int       `json:"min_clicks,omitempty"`
+	MaxClicks      *int       `json:"max_clicks,omitempty"`
+	UploadedBefore *time.Time `json:"uploaded_before,omitempty"`
+	UploadedAfter  *time.Time `json:"uploaded_after,omitempty"`
+}
+
+// IsEmpty 是否没有任何筛选条件
+func (f FileFilter) IsEmpty() bool {
+	return len(f.IDs) == 0 && f.NameContains == "" && len(f.Tags) == 0 &&
+		f.MinClicks == nil && f.MaxClicks == nil &&
+		f.UploadedBefore == nil && f.UploadedAfter == nil
+}
+
+// Match 判断文件是否满足筛选条件
+func (f FileFilter) Match(file *FileData) bool {
+	if len(f.IDs) > 0 {
+		found := false
+		for _, id := range f.IDs {
+			if id == file.ID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.NameContains != "" && !strings.Contains(strings.ToLower(file.Name), strings.ToLower(f.NameContains)) {
+		return false
+	}
+	if !file.HasTags(f.Tags) {
+		return false
+	}
+	if f.MinClicks != nil && file.Clicks < *f.MinClicks {
+		return false
+	}
+	if f.MaxClicks != nil && file.Clicks > *f.MaxClicks {
+		return false
+	}
+	if f.UploadedBefore != nil && !file.UploadAt.Before(*f.UploadedBefore) {
+		return false
+	}
+	if f.UploadedAfter != nil && !file.UploadAt.After(*f.UploadedAfter) {
+		return false
+	}
+	return true
+}
+
+// SetClicks 将文件点击数设置为指定值
+func (s *FileStore) SetClicks(id string, clicks int, reason, actor string) (*FileData, error) {
+	if clicks < 0 {
+		return nil, fmt.Errorf("点击数不能为负数")
+	}
+	return s.changeClicks(id, "set", reason, actor, func(int) int { return clicks })
+}
+
+// AdjustClicks 按有符号增量调整文件点击数
+func (s *FileStore) AdjustClicks(id string, delta int, reason, actor string) (*FileData, error) {
+	if delta == 0 {
+		return nil, fmt.Errorf("调整值不能为0")
+	}
+	return s.changeClicks(id, "adjust", reason, actor, func(old int) int { return old + delta })
+}
+
+func (s *FileStore) changeClicks(id, action, reason, actor string, next func(int) int) (*FileData, error) {
+	s.mu.Lock()
+	file, exists := s.files[id]
+	if !exists {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("文件不存在: %s", id)
+	}
+
+	old := file.Clicks + int(s.drainCounter(id))
+	clicks := next(old)
+	if clicks < 0 {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("调整后点击数不能为负数 (当前: %d)", old)
+	}
+	file.Clicks = clicks
+	result := *file
+	s.markChanged()
+	s.mu.Unlock()
+
+	s.appendAudit([]AuditEntry{{
+		Time:      time.Now(),
+		Action:    action,
+		FileID:    id,
+		Name:      result.Name,
+		OldClicks: old,
+		NewClicks: clicks,
+		Reason:    reason,
+		Actor:     actor,
+	}})
+	return &result, nil
+}
+
+// ResetClicks 将匹配筛选条件的文件点击数清零，返回受影响的审计记录
+func (s *FileStore) ResetClicks(filter FileFilter, reason, actor string) ([]AuditEntry, error) {
+	now := time.Now()
+	entries := make([]AuditEntry, 0)
+
+	s.mu.Lock()
+	for id, file := range s.files {
+		file.Clicks += int(s.drainCounter(id))
+		if !filter.Match(file) || file.Clicks == 0 {
+			continue
+		}
+		entries = append(entries, AuditEntry{
+			Time:      now,
+			Action:    "reset",
+			FileID:    id,
+			Name:      file.Name,
+			OldClicks: file.Clicks,
+			NewClicks: 0,
+			Reason:    reason,
+			Actor:     actor,
+		})
+		file.Clicks = 0
+	}
+	if len(entries) > 0 {
+		s.markChanged()
+	}
+	s.mu.Unlock()
+
+	s.appendAudit(entries)
+	return entries, nil
+}
+
+// GetAuditLog 读取最近的审计记录，limit<=0时返回全部
+func (s *FileStore) GetAuditLog(limit int) ([]AuditEntry, error) {
+	s.auditMu.Lock()
+	defer s.auditMu.Unlock()
+
+	f, err := os.Open(s.auditPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []AuditEntry{}, nil
+		}
+		return nil, fmt.Errorf("读取审计日志失败: %w", err)
+	}
+	defer f.Close()
+
+	entries := make([]AuditEntry, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取审计日志失败: %w", err)
+	}
+
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	return entries, nil
+}
+
+func (s *FileStore) auditPath() string {
+	return filepath.Join(filepath.Dir(s.dataPath), "audit.jsonl")
+}
+
+// 追加审计记录，每条一行JSON
+func (s *FileStore) appendAudit(entries []AuditEntry) {
+	if len(entries) == 0 {
+		return
+	}
+	log := logger.GetInstance()
+
+	s.auditMu.Lock()
+	defer s.auditMu.Unlock()
+
+	f, err := os.OpenFile(s.auditPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		log.Error("❌ 打开审计日志失败: %v", err)
+		return
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			log.Error("❌ 写入审计日志失败: %v", err)
+			return
+		}
+		log.Info("🛠️ 点击数修改 [%s]: %s %d → %d (原因: %s)", entry.Action, entry.Name, entry.OldClicks, entry.NewClicks, entry.Reason)
+	}
+	if err := w.Flush(); err != nil {
+		log.Error("❌ 写入审计日志失败: %v", err)
+	}
+}
+
+// 取出单个文件未合并的点击，调用方需持有写锁
+func (s *FileStore) drainCounter(id string) int64 {
+	if v, ok := s.counters.Load(id); ok {
+		return v.(*stripedCounter).drain()
+	}
+	return 0
+}
+
+// 标记数据变更并通知保存和排行榜更新，调用方需持有写锁
+func (s *FileStore) markChanged() {
+	s.dirty = true
+	s.cacheValid = false // 缓存失效
+	s.triggerSave()
+
+	// 异步通知更新
+	select {
+	case s.updateChan <- struct{}{}:
+	default:
+	}
+}
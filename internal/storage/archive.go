@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"file-ranking/internal/logger"
+)
+
+// 压缩包解压限制，防止压缩炸弹
+const (
+	MaxArchiveEntries   = 1000
+	MaxArchiveEntrySize = 10 * 1024 * 1024
+	MaxArchiveTotalSize = 500 * 1024 * 1024
+)
+
+var (
+	ErrUnsupportedArchive = errors.New("不支持的压缩包格式，仅支持 .zip、.tar.gz 和 .tgz")
+	errEntryTooLarge      = fmt.Errorf("文件大小不能超过%dMB", MaxArchiveEntrySize>>20)
+	errArchiveTooLarge    = fmt.Errorf("压缩包解压后总大小不能超过%dMB", MaxArchiveTotalSize>>20)
+)
+
+// ArchiveEntry 压缩包中一个文件的导入结果
+type ArchiveEntry struct {
+	Name    string
+	Outcome *UploadOutcome
+	Err     error
+}
+
+// IsArchive 根据扩展名判断是否为支持解压的压缩包
+func IsArchive(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.HasSuffix(lower, ".zip") || strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz")
+}
+
+// ImportArchive 把压缩包中的每个文件登记为独立文档，文件名保留压缩包内的目录结构，
+// 单个文件失败不影响其他文件；与已有文件内容相同时按policy处理
+func (s *FileStore) ImportArchive(name string, r io.ReaderAt, size int64, policy string) ([]ArchiveEntry, error) {
+	log := logger.GetInstance()
+	log.Info("📦 开始解压压缩包: %s (大小: %d bytes)", name, size)
+
+	im := &archiveImporter{store: s, policy: policy, budget: MaxArchiveTotalSize}
+	var err error
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		err = im.importZip(r, size)
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		err = im.importTarGz(io.NewSectionReader(r, 0, size))
+	default:
+		err = ErrUnsupportedArchive
+	}
+	if err != nil {
+		log.Error("❌ 解压压缩包失败: %s - %v", name, err)
+		return im.entries, fmt.Errorf("解压压缩包失败: %w", err)
+	}
+
+	log.Info("✅ 压缩包解压完成: %s (%d 个文件)", name, len(im.entries))
+	return im.entries, nil
+}
+
+type archiveImporter struct {
+	store   *FileStore
+	policy  string
+	budget  int64 // 剩余可解压的字节数
+	entries []ArchiveEntry
+}
+
+func (im *archiveImporter) importZip(r io.ReaderAt, size int64) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		name, ok := archiveEntryName(f.Name)
+		if !ok {
+			continue
+		}
+		if !im.reserve() {
+			break
+		}
+		if f.UncompressedSize64 > MaxArchiveEntrySize {
+			im.entries = append(im.entries, ArchiveEntry{Name: name, Err: errEntryTooLarge})
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			im.entries = append(im.entries, ArchiveEntry{Name: name, Err: err})
+			continue
+		}
+		im.add(name, rc)
+		rc.Close()
+	}
+	return nil
+}
+
+func (im *archiveImporter) importTarGz(r io.Reader) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name, ok := archiveEntryName(hdr.Name)
+		if !ok {
+			continue
+		}
+		if !im.reserve() {
+			return nil
+		}
+		if hdr.Size > MaxArchiveEntrySize {
+			im.entries = append(im.entries, ArchiveEntry{Name: name, Err: errEntryTooLarge})
+			continue
+		}
+		im.add(name, tr)
+	}
+}
+
+// 文件数达到上限时停止解压
+func (im *archiveImporter) reserve() bool {
+	if len(im.entries) < MaxArchiveEntries {
+		return true
+	}
+	logger.GetInstance().Warn("⚠️ 压缩包文件数超过 %d 个，其余文件已忽略", MaxArchiveEntries)
+	return false
+}
+
+// 压缩包头部记录的大小可能不可信，实际读取时同样限制大小
+func (im *archiveImporter) add(name string, r io.Reader) {
+	limit := int64(MaxArchiveEntrySize)
+	tooLarge := errEntryTooLarge
+	if im.budget < limit {
+		limit, tooLarge = im.budget, errArchiveTooLarge
+	}
+	lr := &strictLimitReader{r: r, n: limit, err: tooLarge}
+	outcome, err := im.store.UploadFileWithPolicy(name, lr, im.policy)
+	if err != nil && lr.exceeded {
+		err = tooLarge
+	}
+	if outcome != nil {
+		im.budget -= outcome.File.Size
+	}
+	im.entries = append(im.entries, ArchiveEntry{Name: name, Outcome: outcome, Err: err})
+}
+
+// 压缩包内的路径统一为 / 分隔的相对路径，跳过目录穿越和系统生成的隐藏文件
+func archiveEntryName(name string) (string, bool) {
+	name = path.Clean("/" + strings.ReplaceAll(name, "\\", "/"))
+	name = strings.TrimPrefix(name, "/")
+	if name == "" || name == "." {
+		return "", false
+	}
+	if strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(path.Base(name), "._") || path.Base(name) == ".DS_Store" {
+		return "", false
+	}
+	return name, true
+}
+
+// 超过n字节时返回err，而不是像io.LimitReader那样静默截断
+type strictLimitReader struct {
+	r        io.Reader
+	n        int64
+	err      error
+	exceeded bool
+}
+
+func (l *strictLimitReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		var probe [1]byte
+		if n, _ := l.r.Read(probe[:]); n > 0 {
+			l.exceeded = true
+			return 0, l.err
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
@@ -0,0 +1,356 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"file-ranking/internal/logger"
+)
+
+// 清理方式
+const (
+	CleanupDelete  = "delete"  // 直接删除
+	CleanupArchive = "archive" // 移入回收站
+)
+
+// DefaultCleanupInterval 清理任务默认每小时执行一次
+const DefaultCleanupInterval = time.Hour
+
+// 到期文件在清理结果中的规则名
+const expiredRuleName = "expired"
+
+// CleanupRule 按不活跃时长清理文件的规则：最近一次点击、上传和内容修改
+// 都早于 InactiveDays 天前，且满足 Filter 时按 Action 处理
+type CleanupRule struct {
+	Name         string     `json:"name"`
+	Action       string     `json:"action"`
+	InactiveDays int        `json:"inactive_days"`
+	Filter       FileFilter `json:"filter"`
+}
+
+// CleanupPolicy 清理策略：到期文件的处理方式和按顺序匹配的不活跃规则
+type CleanupPolicy struct {
+	// 到期文件的处理方式，为空时不处理到期文件
+	ExpiredAction string        `json:"expired_action"`
+	Rules         []CleanupRule `json:"rules"`
+}
+
+// CleanupCandidate 将被清理的文件及命中的规则
+type CleanupCandidate struct {
+	FileID       string     `json:"file_id"`
+	Name         string     `json:"name"`
+	Clicks       int        `json:"clicks"`
+	Size         int64      `json:"size"`
+	LastActiveAt time.Time  `json:"last_active_at"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	Rule         string     `json:"rule"`
+	Action       string     `json:"action"`
+}
+
+// CleanupReport 一次清理（或预演）的结果
+type CleanupReport struct {
+	Time       time.Time          `json:"time"`
+	DryRun     bool               `json:"dry_run"`
+	Candidates []CleanupCandidate `json:"candidates"`
+	Deleted    int                `json:"deleted"`
+	Archived   int                `json:"archived"`
+	Failed     int                `json:"failed"`
+}
+
+type cleanupState struct {
+	mu     sync.Mutex
+	policy CleanupPolicy
+	last   *CleanupReport
+
+	runMu sync.Mutex // 后台任务和手动执行不同时进行
+}
+
+// 默认不清理任何文件，到期文件的处理方式需要管理员在策略中设置
+func defaultCleanupPolicy() CleanupPolicy {
+	return CleanupPolicy{Rules: []CleanupRule{}}
+}
+
+func validCleanupAction(action string) bool {
+	return action == CleanupDelete || action == CleanupArchive
+}
+
+// Validate 检查清理策略是否有效
+func (p CleanupPolicy) Validate() error {
+	if p.ExpiredAction != "" && !validCleanupAction(p.ExpiredAction) {
+		return fmt.Errorf("不支持的到期处理方式: %s", p.ExpiredAction)
+	}
+	names := make(map[string]bool, len(p.Rules))
+	for i, rule := range p.Rules {
+		name := strings.TrimSpace(rule.Name)
+		if name == "" {
+			return fmt.Errorf("第 %d 条规则缺少名称", i+1)
+		}
+		if name == expiredRuleName || names[name] {
+			return fmt.Errorf("规则名称重复或保留: %s", name)
+		}
+		names[name] = true
+		if !validCleanupAction(rule.Action) {
+			return fmt.Errorf("规则 %s 的处理方式必须是 delete 或 archive", name)
+		}
+		if rule.InactiveDays <= 0 {
+			return fmt.Errorf("规则 %s 的不活跃天数必须大于0", name)
+		}
+	}
+	return nil
+}
+
+func (s *FileStore) cleanupPolicyPath() string {
+	return filepath.Join(filepath.Dir(s.dataPath), "cleanup_policy.json")
+}
+
+// 加载保存的清理策略，文件不存在时使用默认策略
+func (s *FileStore) loadCleanupPolicy() error {
+	policy := defaultCleanupPolicy()
+	data, err := os.ReadFile(s.cleanupPolicyPath())
+	if err == nil {
+		if err := json.Unmarshal(data, &policy); err != nil {
+			return fmt.Errorf("解析清理策略失败: %w", err)
+		}
+		if err := policy.Validate(); err != nil {
+			return fmt.Errorf("清理策略无效: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("读取清理策略失败: %w", err)
+	}
+	if policy.Rules == nil {
+		policy.Rules = []CleanupRule{}
+	}
+
+	s.cleanup.mu.Lock()
+	s.cleanup.policy = policy
+	s.cleanup.mu.Unlock()
+	return nil
+}
+
+// GetCleanupPolicy 当前的清理策略
+func (s *FileStore) GetCleanupPolicy() CleanupPolicy {
+	s.cleanup.mu.Lock()
+	defer s.cleanup.mu.Unlock()
+	policy := s.cleanup.policy
+	policy.Rules = append([]CleanupRule{}, policy.Rules...)
+	return policy
+}
+
+// SetCleanupPolicy 校验并保存清理策略，下次清理时生效
+func (s *FileStore) SetCleanupPolicy(policy CleanupPolicy) error {
+	for i := range policy.Rules {
+		policy.Rules[i].Name = strings.TrimSpace(policy.Rules[i].Name)
+	}
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	if policy.Rules == nil {
+		policy.Rules = []CleanupRule{}
+	}
+
+	data, err := json.MarshalIndent(policy, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化清理策略失败: %w", err)
+	}
+	tempPath := s.cleanupPolicyPath() + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("保存清理策略失败: %w", err)
+	}
+	if err := os.Rename(tempPath, s.cleanupPolicyPath()); err != nil {
+		return fmt.Errorf("保存清理策略失败: %w", err)
+	}
+
+	s.cleanup.mu.Lock()
+	s.cleanup.policy = policy
+	s.cleanup.mu.Unlock()
+	logger.GetInstance().Info("🧹 清理策略已更新: 到期处理 %q, 规则 %d 条", policy.ExpiredAction, len(policy.Rules))
+	return nil
+}
+
+// SetFileExpiry 设置文件的到期时间，nil表示不过期；到期后由清理任务按策略处理
+func (s *FileStore) SetFileExpiry(id string, expiresAt *time.Time) (*FileData, error) {
+	s.mu.Lock()
+	file, exists := s.files[id]
+	if !exists {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("文件不存在: %s", id)
+	}
+	if expiresAt != nil {
+		t := *expiresAt
+		file.ExpiresAt = &t
+	} else {
+		file.ExpiresAt = nil
+	}
+	s.markChanged()
+	result := s.withPending(*file)
+	s.mu.Unlock()
+
+	if expiresAt != nil {
+		logger.GetInstance().Info("⏳ 设置文件到期时间: %s (ID: %s, 到期: %s)", result.Name, id, expiresAt.Format(time.RFC3339))
+	}
+	s.notify(FileMetadataChanged, result)
+	return &result, nil
+}
+
+// 判断文件是否应被清理：先看是否到期，再按顺序匹配不活跃规则。
+// 从导入目录同步的文件以导入目录为准，正在编辑的文件暂不处理
+func (s *FileStore) cleanupMatch(file *FileData, policy CleanupPolicy, lastClick, now time.Time) (CleanupCandidate, bool) {
+	if file.Import != nil || s.GetEditLock(file.ID) != nil {
+		return CleanupCandidate{}, false
+	}
+
+	times := []time.Time{file.ModifiedAt, lastClick}
+	if lastClick.IsZero() && file.Clicks > 0 {
+		// 开始记录点击趋势之前的点击没有时间，按从开始记录时起仍然活跃处理
+		times = append(times, s.seriesSince)
+	}
+	lastActive := file.UploadAt
+	for _, t := range times {
+		if t.After(lastActive) {
+			lastActive = t
+		}
+	}
+	candidate := CleanupCandidate{
+		FileID:       file.ID,
+		Name:         file.Name,
+		Clicks:       file.Clicks,
+		Size:         file.Size,
+		LastActiveAt: lastActive,
+		ExpiresAt:    file.ExpiresAt,
+	}
+
+	if file.ExpiresAt != nil && !now.Before(*file.ExpiresAt) && policy.ExpiredAction != "" {
+		candidate.Rule, candidate.Action = expiredRuleName, policy.ExpiredAction
+		return candidate, true
+	}
+	for _, rule := range policy.Rules {
+		cutoff := now.Add(-time.Duration(rule.InactiveDays) * 24 * time.Hour)
+		if lastActive.Before(cutoff) && rule.Filter.Match(file) {
+			candidate.Rule, candidate.Action = rule.Name, rule.Action
+			return candidate, true
+		}
+	}
+	return CleanupCandidate{}, false
+}
+
+// PlanCleanup 按当前策略列出将被清理的文件，不做任何修改；按最近活跃时间升序
+func (s *FileStore) PlanCleanup(now time.Time) *CleanupReport {
+	s.foldClicks()
+	policy := s.GetCleanupPolicy()
+	lastClicks := s.lastClicks()
+
+	s.mu.RLock()
+	files := make([]FileData, 0, len(s.files))
+	for _, file := range s.files {
+		files = append(files, *file)
+	}
+	s.mu.RUnlock()
+
+	report := &CleanupReport{Time: now, DryRun: true, Candidates: []CleanupCandidate{}}
+	for i := range files {
+		if candidate, ok := s.cleanupMatch(&files[i], policy, lastClicks[files[i].ID], now); ok {
+			report.Candidates = append(report.Candidates, candidate)
+		}
+	}
+	sort.Slice(report.Candidates, func(i, j int) bool {
+		return report.Candidates[i].LastActiveAt.Before(report.Candidates[j].LastActiveAt)
+	})
+	return report
+}
+
+// RunCleanup 按当前策略删除或归档文件，每个文件记入审计日志
+func (s *FileStore) RunCleanup(now time.Time, actor string) *CleanupReport {
+	log := logger.GetInstance()
+	s.cleanup.runMu.Lock()
+	defer s.cleanup.runMu.Unlock()
+
+	policy := s.GetCleanupPolicy()
+	plan := s.PlanCleanup(now)
+	lastClicks := s.lastClicks()
+	report := &CleanupReport{Time: now, Candidates: []CleanupCandidate{}}
+	var audit []AuditEntry
+	for _, candidate := range plan.Candidates {
+		// 预演之后文件可能刚被点击或修改，处理前重新确认
+		file, exists := s.GetFile(candidate.FileID)
+		if !exists {
+			continue
+		}
+		current, ok := s.cleanupMatch(file, policy, lastClicks[file.ID], now)
+		if !ok {
+			continue
+		}
+
+		var err error
+		if current.Action == CleanupArchive {
+			err = s.TrashFile(current.FileID)
+		} else {
+			err = s.RemoveFile(current.FileID, AnyVersion)
+		}
+		if err != nil {
+			log.Error("❌ 清理文件失败: %s (ID: %s) - %v", current.Name, current.FileID, err)
+			report.Failed++
+			continue
+		}
+
+		if current.Action == CleanupArchive {
+			report.Archived++
+		} else {
+			report.Deleted++
+		}
+		report.Candidates = append(report.Candidates, current)
+		audit = append(audit, AuditEntry{
+			Time:      now,
+			Action:    "cleanup-" + current.Action,
+			FileID:    current.FileID,
+			Name:      current.Name,
+			OldClicks: current.Clicks,
+			NewClicks: 0,
+			Reason:    fmt.Sprintf("清理规则 %s (最近活跃: %s)", current.Rule, current.LastActiveAt.Format(time.RFC3339)),
+			Actor:     actor,
+		})
+	}
+	s.appendAudit(audit)
+
+	s.cleanup.mu.Lock()
+	s.cleanup.last = report
+	s.cleanup.mu.Unlock()
+	if len(report.Candidates) > 0 || report.Failed > 0 {
+		log.Info("🧹 清理完成: 删除 %d, 归档 %d, 失败 %d", report.Deleted, report.Archived, report.Failed)
+	}
+	return report
+}
+
+// LastCleanup 最近一次实际执行的清理结果
+func (s *FileStore) LastCleanup() *CleanupReport {
+	s.cleanup.mu.Lock()
+	defer s.cleanup.mu.Unlock()
+	return s.cleanup.last
+}
+
+// StartCleanup 按间隔在后台执行清理，启动时先执行一次
+func (s *FileStore) StartCleanup(interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("清理间隔必须大于0")
+	}
+	logger.GetInstance().Info("🧹 启动清理任务 (间隔: %v)", interval)
+	go func() {
+		s.RunCleanup(time.Now(), "system")
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				s.RunCleanup(now, "system")
+			case <-s.done:
+				return
+			}
+		}
+	}()
+	return nil
+}
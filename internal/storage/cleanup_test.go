@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func cleanupCandidates(s *FileStore, now time.Time) map[string]string {
+	rules := make(map[string]string)
+	for _, c := range s.PlanCleanup(now).Candidates {
+		rules[c.FileID] = c.Rule
+	}
+	return rules
+}
+
+// 默认策略不清理任何文件，包括已到期的文件
+func TestDefaultCleanupPolicyKeepsExpiredFiles(t *testing.T) {
+	s := newTestStore(t, "a")
+	now := time.Now()
+	expired := now.Add(-time.Hour)
+	s.mu.Lock()
+	s.files["a"].UploadAt = now.Add(-24 * time.Hour)
+	s.files["a"].ExpiresAt = &expired
+	s.mu.Unlock()
+
+	if got := cleanupCandidates(s, now); len(got) != 0 {
+		t.Fatalf("默认策略的清理对象 = %v, 期望没有", got)
+	}
+}
+
+// 有点击但没有点击趋势的文件（点击早于开始记录趋势），从开始记录时起算最近活动
+func TestCleanupCountsClicksBeforeSeriesTracking(t *testing.T) {
+	s := newTestStore(t, "clicked", "idle", "recent")
+	now := time.Now()
+	old := now.AddDate(0, 0, -200)
+	s.mu.Lock()
+	for _, f := range s.files {
+		f.UploadAt, f.ModifiedAt = old, old
+	}
+	s.files["clicked"].Clicks = 50
+	s.files["recent"].Clicks = 5
+	s.mu.Unlock()
+	s.recordSeries(map[string]int{"recent": 5}, now.AddDate(0, 0, -100))
+
+	if err := s.SetCleanupPolicy(CleanupPolicy{Rules: []CleanupRule{
+		{Name: "idle-30d", Action: CleanupDelete, InactiveDays: 30},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		seriesSince time.Time
+		want        map[string]string
+	}{
+		// 开始记录趋势不满30天，之前有点击的文件仍可能活跃
+		{"刚开始记录", now.AddDate(0, 0, -10), map[string]string{"idle": "idle-30d", "recent": "idle-30d"}},
+		{"开始记录已久", now.AddDate(0, 0, -60), map[string]string{"clicked": "idle-30d", "idle": "idle-30d", "recent": "idle-30d"}},
+	}
+	for _, tt := range tests {
+		s.mu.Lock()
+		s.seriesSince = tt.seriesSince
+		s.mu.Unlock()
+		got := cleanupCandidates(s, now)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: 清理对象 = %v, 期望 %v", tt.name, got, tt.want)
+			continue
+		}
+		for id, rule := range tt.want {
+			if got[id] != rule {
+				t.Errorf("%s: 清理对象 = %v, 期望 %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+// 开始记录点击趋势的时间保存在数据文件中，重启后不变
+func TestSeriesSincePersisted(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestStore(t, dir)
+	since := s.seriesSince
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s = openTestStore(t, dir)
+	defer s.Close()
+	if !s.seriesSince.Equal(since) {
+		t.Fatalf("重启后 seriesSince = %v, 期望 %v", s.seriesSince, since)
+	}
+}
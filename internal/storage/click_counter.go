@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"math/rand/v2"
+	"sync/atomic"
+)
+
+// 分段数量，热点文件的并发点击分散到不同缓存行
+const counterStripes = 8
+
+// 填充到64字节，避免伪共享
+type paddedCounter struct {
+	n atomic.Int64
+	_ [56]byte
+}
+
+// 分段点击计数器，只记录尚未合并到FileData.Clicks的增量
+type stripedCounter struct {
+	stripes [counterStripes]paddedCounter
+	dirty   atomic.Bool
+}
+
+func (c *stripedCounter) add(delta int64) {
+	c.stripes[rand.Uint32()%counterStripes].n.Add(delta)
+	// 只在首次变脏时写，避免每次点击都写同一缓存行
+	if !c.dirty.Load() {
+		c.dirty.Store(true)
+	}
+}
+
+// 当前未合并的增量
+func (c *stripedCounter) pending() int64 {
+	var sum int64
+	for i := range c.stripes {
+		sum += c.stripes[i].n.Load()
+	}
+	return sum
+}
+
+// 取出并清零增量，合并时调用
+func (c *stripedCounter) drain() int64 {
+	if !c.dirty.Swap(false) {
+		return 0
+	}
+	var sum int64
+	for i := range c.stripes {
+		sum += c.stripes[i].n.Swap(0)
+	}
+	return sum
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// 应用日志写在当前目录的logs下，测试在临时目录中运行，不在源码目录留下日志
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "storage-test")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		log.Fatal(err)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// 并发点击的同时不断合并，合并出的增量总和必须等于点击次数
+func TestStripedCounterConcurrentAddDrain(t *testing.T) {
+	const (
+		writers   = 8
+		perWriter = 20000
+	)
+	var c stripedCounter
+	var drained atomic.Int64
+	stop := make(chan struct{})
+	drainerDone := make(chan struct{})
+	go func() {
+		defer close(drainerDone)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				drained.Add(c.drain())
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				c.add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	close(stop)
+	<-drainerDone
+	drained.Add(c.drain())
+
+	if got, want := drained.Load(), int64(writers*perWriter); got != want {
+		t.Fatalf("合并后的点击数 = %d, 期望 %d", got, want)
+	}
+	if p := c.pending(); p != 0 {
+		t.Fatalf("合并后仍有未合并的增量: %d", p)
+	}
+}
+
+// 创建临时目录中的存储并直接登记指定的文件，不经过上传和日志
+func newTestStore(tb testing.TB, ids ...string) *FileStore {
+	tb.Helper()
+	out := log.Writer()
+	log.SetOutput(io.Discard)
+	tb.Cleanup(func() { log.SetOutput(out) })
+
+	dir := tb.TempDir()
+	s, err := NewFileStore(filepath.Join(dir, "files.json"), filepath.Join(dir, "uploads"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { s.Close() })
+
+	s.mu.Lock()
+	for _, id := range ids {
+		s.files[id] = &FileData{ID: id, Name: id + ".txt"}
+		s.counters.Store(id, &stripedCounter{})
+	}
+	s.mu.Unlock()
+	return s
+}
+
+// 改为分段计数器之前的点击路径：每次点击都获取元数据写锁
+func mutexIncrementClick(s *FileStore, id string) {
+	s.mu.Lock()
+	if file, ok := s.files[id]; ok {
+		file.Clicks++
+		s.dirty = true
+		s.cacheValid = false
+	}
+	s.mu.Unlock()
+}
+
+// 同一个热点文件的并发点击，分段计数器
+func BenchmarkIncrementClickParallel(b *testing.B) {
+	const id = "bench"
+	s := newTestStore(b, id)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := s.IncrementClick(id); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+// 同一个热点文件的并发点击，原来的互斥锁路径
+func BenchmarkIncrementClickMutexParallel(b *testing.B) {
+	const id = "bench"
+	s := newTestStore(b, id)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			mutexIncrementClick(s, id)
+		}
+	})
+}
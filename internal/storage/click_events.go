@@ -0,0 +1,422 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"file-ranking/internal/logger"
+)
+
+// 点击来源
+const (
+	SourceREST      = "rest"
+	SourceBulk      = "bulk"
+	SourceWebSocket = "websocket"
+)
+
+// 事件保留策略
+const (
+	RetentionDrop      = "drop"
+	RetentionAggregate = "aggregate"
+)
+
+const (
+	eventFlushInterval = time.Second
+	eventDayLayout     = "2006-01-02"
+)
+
+// ClickEvent 单次点击事件，字段名压缩以减少磁盘占用
+type ClickEvent struct {
+	Time      time.Time `json:"t"`
+	FileID    string    `json:"f"`
+	Count     int       `json:"n,omitempty"`
+	Client    string    `json:"c,omitempty"`
+	UserAgent string    `json:"ua,omitempty"`
+	Referrer  string    `json:"r,omitempty"`
+	Source    string    `json:"s"`
+}
+
+// EventAggregate 过期事件按天、文件汇总后的结果
+type EventAggregate struct {
+	Date     string         `json:"date"`
+	FileID   string         `json:"file_id"`
+	Clicks   int            `json:"clicks"`
+	BySource map[string]int `json:"by_source"`
+	Clients  int            `json:"clients"`
+}
+
+// EventRetention 事件保留策略，MaxAge为0表示永久保留
+type EventRetention struct {
+	MaxAge time.Duration `json:"max_age"`
+	Mode   string        `json:"mode"`
+}
+
+// EventQuery 事件查询条件
+type EventQuery struct {
+	FileID string
+	Client string
+	Source string
+	From   time.Time
+	To     time.Time
+	Limit  int
+}
+
+// EventQueryResult 查询结果，Events按时间倒序
+type EventQueryResult struct {
+	Events     []ClickEvent     `json:"events"`
+	Aggregates []EventAggregate `json:"aggregates"`
+	Dropped    uint64           `json:"dropped"`
+}
+
+// PruneReport 一次保留策略执行的结果
+type PruneReport struct {
+	Mode       string   `json:"mode"`
+	Cutoff     string   `json:"cutoff"`
+	Days       []string `json:"days"`
+	Events     int      `json:"events"`
+	Aggregates int      `json:"aggregates"`
+}
+
+// 点击事件日志，按天分文件追加写入
+type clickEventLog struct {
+	dir       string
+	ch        chan ClickEvent
+	dropped   atomic.Uint64
+	mu        sync.Mutex // 保护磁盘文件
+	retention EventRetention
+	exited    chan struct{}
+}
+
+func newClickEventLog(dir string) *clickEventLog {
+	return &clickEventLog{
+		dir:       dir,
+		ch:        make(chan ClickEvent, 10000),
+		retention: EventRetention{MaxAge: 30 * 24 * time.Hour, Mode: RetentionAggregate},
+		exited:    make(chan struct{}),
+	}
+}
+
+// RecordClickEvents 异步记录点击事件，队列满时丢弃并计数
+func (s *FileStore) RecordClickEvents(events ...ClickEvent) {
+	for _, ev := range events {
+		if ev.Time.IsZero() {
+			ev.Time = time.Now()
+		}
+		select {
+		case s.events.ch <- ev:
+		default:
+			s.events.dropped.Add(1)
+		}
+	}
+}
+
+// SetEventRetention 设置事件保留策略
+func (s *FileStore) SetEventRetention(policy EventRetention) error {
+	if policy.Mode != RetentionDrop && policy.Mode != RetentionAggregate {
+		return fmt.Errorf("不支持的保留策略: %s", policy.Mode)
+	}
+	s.events.mu.Lock()
+	s.events.retention = policy
+	s.events.mu.Unlock()
+	return nil
+}
+
+// 事件写入循环，定期批量追加到当天文件，每小时执行一次保留策略
+func (s *FileStore) eventWriter() {
+	el := s.events
+	defer close(el.exited)
+
+	flushTicker := time.NewTicker(eventFlushInterval)
+	defer flushTicker.Stop()
+	pruneTicker := time.NewTicker(time.Hour)
+	defer pruneTicker.Stop()
+
+	var pending []ClickEvent
+	for {
+		select {
+		case ev := <-el.ch:
+			pending = append(pending, ev)
+		case <-flushTicker.C:
+			pending = el.flush(pending)
+		case <-pruneTicker.C:
+			if _, err := s.PruneClickEvents(time.Now()); err != nil {
+				logger.GetInstance().Error("❌ 清理点击事件失败: %v", err)
+			}
+		case <-s.done:
+			for {
+				select {
+				case ev := <-el.ch:
+					pending = append(pending, ev)
+				default:
+					el.flush(pending)
+					return
+				}
+			}
+		}
+	}
+}
+
+func (el *clickEventLog) dayPath(day string) string {
+	return filepath.Join(el.dir, day+".jsonl")
+}
+
+func (el *clickEventLog) aggregatePath(day string) string {
+	return filepath.Join(el.dir, day+".agg.json")
+}
+
+// 按天分组写入，返回清空后的切片以便复用
+func (el *clickEventLog) flush(pending []ClickEvent) []ClickEvent {
+	if len(pending) == 0 {
+		return pending
+	}
+	log := logger.GetInstance()
+
+	el.mu.Lock()
+	defer el.mu.Unlock()
+
+	if err := os.MkdirAll(el.dir, 0755); err != nil {
+		log.Error("❌ 创建事件目录失败: %v", err)
+		return pending[:0]
+	}
+
+	byDay := make(map[string][]ClickEvent)
+	for _, ev := range pending {
+		day := ev.Time.Format(eventDayLayout)
+		byDay[day] = append(byDay[day], ev)
+	}
+
+	for day, events := range byDay {
+		f, err := os.OpenFile(el.dayPath(day), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			log.Error("❌ 打开事件文件失败: %v", err)
+			continue
+		}
+		w := bufio.NewWriter(f)
+		enc := json.NewEncoder(w)
+		for _, ev := range events {
+			if err := enc.Encode(ev); err != nil {
+				log.Error("❌ 写入点击事件失败: %v", err)
+				break
+			}
+		}
+		if err := w.Flush(); err != nil {
+			log.Error("❌ 写入点击事件失败: %v", err)
+		}
+		f.Close()
+	}
+	return pending[:0]
+}
+
+// 读取某天的原始事件
+func (el *clickEventLog) readDay(day string) ([]ClickEvent, error) {
+	f, err := os.Open(el.dayPath(day))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []ClickEvent
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var ev ClickEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			continue
+		}
+		events = append(events, ev)
+	}
+	return events, scanner.Err()
+}
+
+func (el *clickEventLog) readAggregates(day string) ([]EventAggregate, error) {
+	data, err := os.ReadFile(el.aggregatePath(day))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var aggs []EventAggregate
+	if err := json.Unmarshal(data, &aggs); err != nil {
+		return nil, err
+	}
+	return aggs, nil
+}
+
+// QueryClickEvents 按文件、客户端、来源和时间范围查询点击事件
+func (s *FileStore) QueryClickEvents(q EventQuery) (*EventQueryResult, error) {
+	if q.To.IsZero() {
+		q.To = time.Now()
+	}
+	if q.From.IsZero() {
+		q.From = q.To.Add(-24 * time.Hour)
+	}
+	if q.To.Before(q.From) {
+		return nil, fmt.Errorf("结束时间不能早于开始时间")
+	}
+	if q.Limit <= 0 {
+		q.Limit = 1000
+	}
+
+	// 队列中尚未落盘的事件最多延迟 eventFlushInterval
+	el := s.events
+	el.mu.Lock()
+	defer el.mu.Unlock()
+
+	result := &EventQueryResult{
+		Events:     make([]ClickEvent, 0),
+		Aggregates: make([]EventAggregate, 0),
+		Dropped:    el.dropped.Load(),
+	}
+
+	first := bucketStart(q.From, GranularityDay)
+	for day := bucketStart(q.To, GranularityDay); !day.Before(first); day = day.AddDate(0, 0, -1) {
+		name := day.Format(eventDayLayout)
+
+		aggs, err := el.readAggregates(name)
+		if err != nil {
+			return nil, fmt.Errorf("读取事件汇总失败: %w", err)
+		}
+		for _, agg := range aggs {
+			if q.FileID != "" && agg.FileID != q.FileID {
+				continue
+			}
+			// 汇总只按文件记录，没有客户端和来源明细，筛选结果会随保留策略变化
+			if q.Client != "" || q.Source != "" {
+				return nil, fmt.Errorf("%s 的点击事件已汇总，不能按客户端或来源筛选，请缩小时间范围", name)
+			}
+			result.Aggregates = append(result.Aggregates, agg)
+		}
+
+		if len(result.Events) >= q.Limit {
+			continue
+		}
+		events, err := el.readDay(name)
+		if err != nil {
+			return nil, fmt.Errorf("读取点击事件失败: %w", err)
+		}
+		for i := len(events) - 1; i >= 0 && len(result.Events) < q.Limit; i-- {
+			if q.match(events[i]) {
+				result.Events = append(result.Events, events[i])
+			}
+		}
+	}
+	return result, nil
+}
+
+func (q EventQuery) match(ev ClickEvent) bool {
+	if ev.Time.Before(q.From) || ev.Time.After(q.To) {
+		return false
+	}
+	if q.FileID != "" && ev.FileID != q.FileID {
+		return false
+	}
+	if q.Client != "" && ev.Client != q.Client {
+		return false
+	}
+	if q.Source != "" && ev.Source != q.Source {
+		return false
+	}
+	return true
+}
+
+// PruneClickEvents 对早于保留期限的天执行保留策略：删除或汇总后删除原始事件
+func (s *FileStore) PruneClickEvents(now time.Time) (*PruneReport, error) {
+	el := s.events
+	el.mu.Lock()
+	defer el.mu.Unlock()
+
+	report := &PruneReport{Mode: el.retention.Mode, Days: make([]string, 0)}
+	if el.retention.MaxAge <= 0 {
+		return report, nil
+	}
+	cutoff := bucketStart(now.Add(-el.retention.MaxAge), GranularityDay)
+	report.Cutoff = cutoff.Format(eventDayLayout)
+
+	entries, err := os.ReadDir(el.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return report, nil
+		}
+		return nil, fmt.Errorf("读取事件目录失败: %w", err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, ".jsonl") {
+			continue
+		}
+		day := strings.TrimSuffix(name, ".jsonl")
+		t, err := time.ParseInLocation(eventDayLayout, day, time.Local)
+		if err != nil || !t.Before(cutoff) {
+			continue
+		}
+
+		events, err := el.readDay(day)
+		if err != nil {
+			return nil, fmt.Errorf("读取点击事件失败: %w", err)
+		}
+		if el.retention.Mode == RetentionAggregate {
+			aggs := aggregateEvents(day, events)
+			data, err := json.Marshal(aggs)
+			if err != nil {
+				return nil, fmt.Errorf("序列化事件汇总失败: %w", err)
+			}
+			if err := os.WriteFile(el.aggregatePath(day), data, 0644); err != nil {
+				return nil, fmt.Errorf("写入事件汇总失败: %w", err)
+			}
+			report.Aggregates += len(aggs)
+		}
+		if err := os.Remove(el.dayPath(day)); err != nil {
+			return nil, fmt.Errorf("删除点击事件失败: %w", err)
+		}
+		report.Days = append(report.Days, day)
+		report.Events += len(events)
+	}
+
+	if len(report.Days) > 0 {
+		logger.GetInstance().Info("🧹 点击事件清理完成 [%s]: %d 天, %d 条事件", report.Mode, len(report.Days), report.Events)
+	}
+	return report, nil
+}
+
+// 按文件汇总一天的事件
+func aggregateEvents(day string, events []ClickEvent) []EventAggregate {
+	byFile := make(map[string]*EventAggregate)
+	clients := make(map[string]map[string]bool)
+	for _, ev := range events {
+		agg, ok := byFile[ev.FileID]
+		if !ok {
+			agg = &EventAggregate{Date: day, FileID: ev.FileID, BySource: make(map[string]int)}
+			byFile[ev.FileID] = agg
+			clients[ev.FileID] = make(map[string]bool)
+		}
+		n := ev.Count
+		if n == 0 {
+			n = 1
+		}
+		agg.Clicks += n
+		agg.BySource[ev.Source] += n
+		if ev.Client != "" {
+			clients[ev.FileID][ev.Client] = true
+		}
+	}
+
+	result := make([]EventAggregate, 0, len(byFile))
+	for id, agg := range byFile {
+		agg.Clients = len(clients[id])
+		result = append(result, *agg)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Clicks > result[j].Clicks })
+	return result
+}
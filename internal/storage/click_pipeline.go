@@ -0,0 +1,332 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// 点击聚合窗口，窗口内的点击在一次加锁中提交
+const clickWindow = 50 * time.Millisecond
+
+// 批量操作类型
+type batchOperation struct {
+	type_    string
+	id       string
+	clicks   int
+	enqueued time.Time
+	done     chan error // 为nil表示不等待提交
+}
+
+// 点击管道内部统计
+type pipelineStats struct {
+	flushes      atomic.Uint64
+	committed    atomic.Uint64
+	lastLatency  atomic.Int64
+	maxLatency   atomic.Int64
+	totalLatency atomic.Int64
+}
+
+// PipelineStats 点击管道运行状态
+type PipelineStats struct {
+	QueueDepth         int     `json:"queue_depth"`
+	QueueCapacity      int     `json:"queue_capacity"`
+	BatchSize          int     `json:"batch_size"`
+	WindowMs           float64 `json:"window_ms"`
+	Flushes            uint64  `json:"flushes"`
+	ClicksCommitted    uint64  `json:"clicks_committed"`
+	LastFlushLatencyMs float64 `json:"last_flush_latency_ms"`
+	MaxFlushLatencyMs  float64 `json:"max_flush_latency_ms"`
+	AvgFlushLatencyMs  float64 `json:"avg_flush_latency_ms"`
+}
+
+// SubmitClicks 将点击放入批量队列，wait为true时等待提交完成
+func (s *FileStore) SubmitClicks(id string, clicks int, wait bool) error {
+	if clicks <= 0 {
+		return fmt.Errorf("点击次数必须大于0")
+	}
+	if _, exists := s.counters.Load(id); !exists {
+		return fmt.Errorf("文件不存在: %s", id)
+	}
+
+	op := batchOperation{
+		type_:    "click",
+		id:       id,
+		clicks:   clicks,
+		enqueued: time.Now(),
+	}
+	if wait {
+		op.done = make(chan error, 1)
+	}
+
+	select {
+	case s.batchChan <- op:
+	case <-s.done:
+		return fmt.Errorf("存储已关闭")
+	}
+
+	if !wait {
+		return nil
+	}
+	select {
+	case err := <-op.done:
+		return err
+	case <-s.pipelineExited:
+		select {
+		case err := <-op.done:
+			return err
+		default:
+			return fmt.Errorf("存储已关闭")
+		}
+	}
+}
+
+// GetPipelineStats 获取点击管道状态
+func (s *FileStore) GetPipelineStats() PipelineStats {
+	st := &s.pipelineStats
+	flushes := st.flushes.Load()
+
+	stats := PipelineStats{
+		QueueDepth:         len(s.batchChan),
+		QueueCapacity:      cap(s.batchChan),
+		BatchSize:          s.batchSize,
+		WindowMs:           durationMs(clickWindow),
+		Flushes:            flushes,
+		ClicksCommitted:    st.committed.Load(),
+		LastFlushLatencyMs: durationMs(time.Duration(st.lastLatency.Load())),
+		MaxFlushLatencyMs:  durationMs(time.Duration(st.maxLatency.Load())),
+	}
+	if flushes > 0 {
+		stats.AvgFlushLatencyMs = durationMs(time.Duration(st.totalLatency.Load() / int64(flushes)))
+	}
+	return stats
+}
+
+// 点击管道：收集队列中的点击和分段计数器信号，按窗口或批量大小提交
+func (s *FileStore) clickPipeline() {
+	defer close(s.pipelineExited)
+
+	var (
+		pending []batchOperation
+		timer   *time.Timer
+		timerC  <-chan time.Time
+		oldest  time.Time
+	)
+
+	arm := func(at time.Time) {
+		if timerC == nil {
+			oldest = at
+			timer = time.NewTimer(clickWindow)
+			timerC = timer.C
+		}
+	}
+	flush := func() {
+		if timer != nil {
+			timer.Stop()
+			timer, timerC = nil, nil
+		}
+		s.commitClicks(pending, oldest)
+		pending = pending[:0]
+	}
+
+	for {
+		select {
+		case op := <-s.batchChan:
+			pending = append(pending, op)
+			arm(op.enqueued)
+			if len(pending) >= s.batchSize {
+				flush()
+			}
+		case <-s.clickSignal:
+			arm(time.Now())
+		case <-timerC:
+			flush()
+		case <-s.done:
+			// 关闭前提交队列中剩余的点击
+			for {
+				select {
+				case op := <-s.batchChan:
+					pending = append(pending, op)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// 将所有分段计数器的增量写入FileData.Clicks
+func (s *FileStore) foldClicks() {
+	s.commitClicks(nil, time.Time{})
+}
+
+// 在一次加锁中提交批量点击和分段计数器增量，并通知等待方
+func (s *FileStore) commitClicks(ops []batchOperation, oldest time.Time) {
+	batch := make(map[string]int, len(ops))
+	for _, op := range ops {
+		batch[op.id] += op.clicks
+		if op.enqueued.Before(oldest) {
+			oldest = op.enqueued
+		}
+	}
+
+	s.mu.Lock()
+	s.counters.Range(func(key, value interface{}) bool {
+		if delta := value.(*stripedCounter).drain(); delta != 0 {
+			batch[key.(string)] += int(delta)
+		}
+		return true
+	})
+
+	var missing map[string]bool
+	files, total := 0, 0
+	for id, clicks := range batch {
+		file, ok := s.files[id]
+		if !ok {
+			if missing == nil {
+				missing = make(map[string]bool)
+			}
+			missing[id] = true
+			delete(batch, id)
+			continue
+		}
+		file.Clicks += clicks
+		files++
+		total += clicks
+	}
+
+	if total > 0 {
+		s.dirty = true
+		s.cacheValid = false // 缓存失效
+
+		// 异步通知更新
+		select {
+		case s.updateChan <- struct{}{}:
+		default:
+		}
+	}
+	s.mu.Unlock()
+
+	s.recordSeries(batch, time.Now())
+
+	for _, op := range ops {
+		if op.done == nil {
+			continue
+		}
+		if missing[op.id] {
+			op.done <- fmt.Errorf("文件不存在: %s", op.id)
+		} else {
+			op.done <- nil
+		}
+	}
+
+	if total == 0 || oldest.IsZero() {
+		return
+	}
+
+	latency := time.Since(oldest)
+	s.recordFlush(total, latency)
+	log.Printf("👆 批量提交点击: %d 个文件, %d 次点击, 延迟 %v", files, total, latency)
+}
+
+func (s *FileStore) recordFlush(clicks int, latency time.Duration) {
+	st := &s.pipelineStats
+	st.flushes.Add(1)
+	st.committed.Add(uint64(clicks))
+	st.lastLatency.Store(int64(latency))
+	st.totalLatency.Add(int64(latency))
+	for {
+		cur := st.maxLatency.Load()
+		if int64(latency) <= cur || st.maxLatency.CompareAndSwap(cur, int64(latency)) {
+			break
+		}
+	}
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// ClickResult 批量点击后单个文件的点击数和排名
+type ClickResult struct {
+	FileID string `json:"file_id"`
+	Name   string `json:"name"`
+	Added  int    `json:"added"`
+	Clicks int    `json:"clicks"`
+	Rank   int    `json:"rank"`
+}
+
+// BulkClickError 批量点击校验失败，Errors按文件ID记录原因
+type BulkClickError struct {
+	Errors map[string]string
+}
+
+func (e *BulkClickError) Error() string {
+	return fmt.Sprintf("批量点击校验失败: %d 个文件", len(e.Errors))
+}
+
+// ApplyClicks 原子地为多个文件增加点击，任一文件校验失败则全部不生效
+func (s *FileStore) ApplyClicks(counts map[string]int) ([]ClickResult, error) {
+	if len(counts) == 0 {
+		return nil, fmt.Errorf("点击列表不能为空")
+	}
+
+	s.mu.Lock()
+	errs := make(map[string]string)
+	for id, count := range counts {
+		if _, exists := s.files[id]; !exists {
+			errs[id] = "文件不存在"
+		} else if count <= 0 {
+			errs[id] = "点击次数必须大于0"
+		}
+	}
+	if len(errs) > 0 {
+		s.mu.Unlock()
+		return nil, &BulkClickError{Errors: errs}
+	}
+
+	total := 0
+	for id, count := range counts {
+		s.files[id].Clicks += count
+		total += count
+	}
+	s.dirty = true
+	s.cacheValid = false // 缓存失效
+
+	// 结果取自加锁时校验过的文件，排名包含分段计数器中尚未合并的点击
+	all := make([]int, 0, len(s.files))
+	for _, file := range s.files {
+		all = append(all, s.withPending(*file).Clicks)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(all)))
+	results := make([]ClickResult, 0, len(counts))
+	for id, count := range counts {
+		file := s.withPending(*s.files[id])
+		results = append(results, ClickResult{
+			FileID: id,
+			Name:   file.Name,
+			Added:  count,
+			Clicks: file.Clicks,
+			Rank:   sort.Search(len(all), func(i int) bool { return all[i] <= file.Clicks }) + 1,
+		})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Rank < results[j].Rank })
+
+	s.recordSeries(counts, time.Now())
+	s.triggerSave()
+
+	// 异步通知更新
+	select {
+	case s.updateChan <- struct{}{}:
+	default:
+	}
+
+	log.Printf("👆 批量点击: %d 个文件, %d 次点击", len(counts), total)
+
+	return results, nil
+}
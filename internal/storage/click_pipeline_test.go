@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"sync"
+	"testing"
+)
+
+// 等待提交的点击在确认返回时已经写入文件数据
+func TestSubmitClicksVisibleAfterAck(t *testing.T) {
+	s := newTestStore(t, "a")
+
+	if err := s.SubmitClicks("a", 3, true); err != nil {
+		t.Fatalf("SubmitClicks: %v", err)
+	}
+	s.mu.RLock()
+	clicks := s.files["a"].Clicks
+	s.mu.RUnlock()
+	if clicks != 3 {
+		t.Fatalf("确认后的点击数 = %d, 期望 3", clicks)
+	}
+
+	stats := s.GetPipelineStats()
+	if stats.BatchSize != 100 {
+		t.Fatalf("BatchSize = %d, 期望 100", stats.BatchSize)
+	}
+	if stats.ClicksCommitted != 3 || stats.Flushes != 1 {
+		t.Fatalf("提交统计 = %d 次点击 / %d 次刷新, 期望 3 / 1", stats.ClicksCommitted, stats.Flushes)
+	}
+}
+
+// 并发提交被合并成少量批次，且没有点击丢失
+func TestSubmitClicksBatched(t *testing.T) {
+	s := newTestStore(t, "a", "b")
+
+	const submits = 250
+	var wg sync.WaitGroup
+	errs := make(chan error, submits)
+	for i := 0; i < submits; i++ {
+		id := "a"
+		if i%2 == 1 {
+			id = "b"
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- s.SubmitClicks(id, 1, true)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("SubmitClicks: %v", err)
+		}
+	}
+
+	a, _ := s.GetFile("a")
+	b, _ := s.GetFile("b")
+	if a.Clicks+b.Clicks != submits {
+		t.Fatalf("点击总数 = %d, 期望 %d", a.Clicks+b.Clicks, submits)
+	}
+	stats := s.GetPipelineStats()
+	if stats.ClicksCommitted != submits {
+		t.Fatalf("ClicksCommitted = %d, 期望 %d", stats.ClicksCommitted, submits)
+	}
+	if stats.Flushes >= submits {
+		t.Fatalf("Flushes = %d, 点击没有被合并成批次", stats.Flushes)
+	}
+}
+
+// 文件在提交前被删除时，等待方收到错误而不是静默成功
+func TestSubmitClicksMissingFile(t *testing.T) {
+	s := newTestStore(t, "a")
+	if err := s.SubmitClicks("nope", 1, true); err == nil {
+		t.Fatal("未知文件应返回错误")
+	}
+
+	s.mu.Lock()
+	delete(s.files, "a")
+	s.mu.Unlock()
+	if err := s.SubmitClicks("a", 1, true); err == nil {
+		t.Fatal("已删除的文件应返回错误")
+	}
+}
+
+// 批量点击中任一文件无效时全部不生效
+func TestApplyClicksAllOrNothing(t *testing.T) {
+	s := newTestStore(t, "a", "b")
+
+	_, err := s.ApplyClicks(map[string]int{"a": 2, "b": 1, "nope": 1})
+	bulk, ok := err.(*BulkClickError)
+	if !ok {
+		t.Fatalf("err = %v, 期望 *BulkClickError", err)
+	}
+	if _, ok := bulk.Errors["nope"]; !ok || len(bulk.Errors) != 1 {
+		t.Fatalf("Errors = %v, 期望只有 nope", bulk.Errors)
+	}
+	for _, id := range []string{"a", "b"} {
+		if f, _ := s.GetFile(id); f.Clicks != 0 {
+			t.Fatalf("%s 的点击数 = %d, 校验失败后应为 0", id, f.Clicks)
+		}
+	}
+
+	results, err := s.ApplyClicks(map[string]int{"a": 2, "b": 5})
+	if err != nil {
+		t.Fatalf("ApplyClicks: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("结果数 = %d, 期望 2", len(results))
+	}
+	if r := results[0]; r.FileID != "b" || r.Clicks != 5 || r.Added != 5 || r.Rank != 1 {
+		t.Fatalf("results[0] = %+v", r)
+	}
+	if r := results[1]; r.FileID != "a" || r.Clicks != 2 || r.Rank != 2 {
+		t.Fatalf("results[1] = %+v", r)
+	}
+}
@@ -0,0 +1,327 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 时间粒度
+const (
+	GranularityMinute = "minute"
+	GranularityHour   = "hour"
+	GranularityDay    = "day"
+)
+
+// 各粒度保留时长，按天统计的数据永久保留
+const (
+	minuteRetention = 48 * time.Hour
+	hourRetention   = 90 * 24 * time.Hour
+	seriesPruneGap  = time.Hour
+
+	maxSeriesPoints = 10000
+)
+
+// 单个文件的分桶点击数，键为桶起始时间的Unix秒
+type clickSeries struct {
+	Minute map[int64]int `json:"minute"`
+	Hour   map[int64]int `json:"hour"`
+	Day    map[int64]int `json:"day"`
+}
+
+func newClickSeries() *clickSeries {
+	return &clickSeries{
+		Minute: make(map[int64]int),
+		Hour:   make(map[int64]int),
+		Day:    make(map[int64]int),
+	}
+}
+
+func (cs *clickSeries) buckets(granularity string) map[int64]int {
+	switch granularity {
+	case GranularityMinute:
+		return cs.Minute
+	case GranularityHour:
+		return cs.Hour
+	default:
+		return cs.Day
+	}
+}
+
+// SeriesPoint 时间序列中的一个点
+type SeriesPoint struct {
+	Time   time.Time `json:"time"`
+	Clicks int       `json:"clicks"`
+}
+
+// ClickSeries 文件在时间范围内的点击序列
+type ClickSeries struct {
+	FileID      string        `json:"file_id"`
+	Granularity string        `json:"granularity"`
+	From        time.Time     `json:"from"`
+	To          time.Time     `json:"to"`
+	Total       int           `json:"total"`
+	Points      []SeriesPoint `json:"points"`
+}
+
+// ValidGranularity 检查时间粒度是否支持
+func ValidGranularity(granularity string) bool {
+	switch granularity {
+	case GranularityMinute, GranularityHour, GranularityDay:
+		return true
+	}
+	return false
+}
+
+func bucketStart(t time.Time, granularity string) time.Time {
+	return bucketStartIn(t, granularity, time.Local)
+}
+
+// 按loc的时钟计算桶的起始时间
+func bucketStartIn(t time.Time, granularity string, loc *time.Location) time.Time {
+	switch granularity {
+	case GranularityMinute:
+		return t.Truncate(time.Minute)
+	case GranularityHour:
+		// 小时桶按本地时钟的整点划分，半小时时区也对齐到本地整点
+		_, offset := t.In(loc).Zone()
+		shift := time.Duration(offset) * time.Second
+		return t.Add(shift).Truncate(time.Hour).Add(-shift).In(loc)
+	default:
+		// 天桶按服务器本地时区的零点划分，与参数带的时区无关
+		y, m, d := t.In(loc).Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, loc)
+	}
+}
+
+func nextBucket(t time.Time, granularity string) time.Time {
+	switch granularity {
+	case GranularityMinute:
+		return t.Add(time.Minute)
+	case GranularityHour:
+		return bucketStart(t.Add(time.Hour), GranularityHour)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+// 记录一批点击到时间桶
+func (s *FileStore) recordSeries(batch map[string]int, at time.Time) {
+	if len(batch) == 0 {
+		return
+	}
+
+	minute := bucketStart(at, GranularityMinute).Unix()
+	hour := bucketStart(at, GranularityHour).Unix()
+	day := bucketStart(at, GranularityDay).Unix()
+
+	s.seriesMu.Lock()
+	defer s.seriesMu.Unlock()
+
+	for id, clicks := range batch {
+		cs, ok := s.series[id]
+		if !ok {
+			cs = newClickSeries()
+			s.series[id] = cs
+		}
+		cs.Minute[minute] += clicks
+		cs.Hour[hour] += clicks
+		cs.Day[day] += clicks
+	}
+
+	if at.Sub(s.seriesPruned) > seriesPruneGap {
+		s.pruneSeries(at)
+		s.seriesPruned = at
+	}
+}
+
+// 清理超过保留时长的分钟和小时桶，调用方需持有seriesMu
+func (s *FileStore) pruneSeries(now time.Time) {
+	minuteCutoff := now.Add(-minuteRetention).Unix()
+	hourCutoff := now.Add(-hourRetention).Unix()
+	for _, cs := range s.series {
+		for ts := range cs.Minute {
+			if ts < minuteCutoff {
+				delete(cs.Minute, ts)
+			}
+		}
+		for ts := range cs.Hour {
+			if ts < hourCutoff {
+				delete(cs.Hour, ts)
+			}
+		}
+	}
+}
+
+func (s *FileStore) removeSeries(id string) {
+	s.seriesMu.Lock()
+	delete(s.series, id)
+	s.seriesMu.Unlock()
+}
+
+// 每个文件最近一次点击的时间，取有点击的最新时间桶的结束时间，
+// 分钟桶保留48小时，更早的点击精确到小时或天
+func (s *FileStore) lastClicks() map[string]time.Time {
+	s.seriesMu.Lock()
+	defer s.seriesMu.Unlock()
+
+	last := make(map[string]time.Time, len(s.series))
+	for id, cs := range s.series {
+		var latest time.Time
+		for _, g := range []struct {
+			buckets map[int64]int
+			width   time.Duration
+		}{{cs.Minute, time.Minute}, {cs.Hour, time.Hour}, {cs.Day, 24 * time.Hour}} {
+			for ts, clicks := range g.buckets {
+				if end := time.Unix(ts, 0).Add(g.width); clicks > 0 && end.After(latest) {
+					latest = end
+				}
+			}
+		}
+		if !latest.IsZero() {
+			last[id] = latest
+		}
+	}
+	return last
+}
+
+// GetClickSeries 获取文件在[from, to]内按粒度汇总的点击序列，空桶补0
+func (s *FileStore) GetClickSeries(id string, from, to time.Time, granularity string) (*ClickSeries, error) {
+	if !ValidGranularity(granularity) {
+		return nil, fmt.Errorf("不支持的时间粒度: %s", granularity)
+	}
+	if _, exists := s.GetFile(id); !exists {
+		return nil, fmt.Errorf("文件不存在: %s", id)
+	}
+	if to.Before(from) {
+		return nil, fmt.Errorf("结束时间不能早于开始时间")
+	}
+
+	// 参数可能带其他时区，统一到桶所用的本地时区
+	from, to = from.In(time.Local), to.In(time.Local)
+	start := bucketStart(from, granularity)
+	result := &ClickSeries{
+		FileID:      id,
+		Granularity: granularity,
+		From:        start,
+		To:          to,
+		Points:      make([]SeriesPoint, 0),
+	}
+
+	s.seriesMu.Lock()
+	defer s.seriesMu.Unlock()
+
+	var buckets map[int64]int
+	if cs, ok := s.series[id]; ok {
+		buckets = cs.buckets(granularity)
+	}
+
+	// 分段计数器中尚未合并的点击计入当前桶，读取时不获取元数据写锁
+	var pending int
+	current := bucketStart(time.Now(), granularity).Unix()
+	if v, ok := s.counters.Load(id); ok {
+		pending = int(v.(*stripedCounter).pending())
+	}
+
+	for t := start; !t.After(to); t = nextBucket(t, granularity) {
+		if len(result.Points) >= maxSeriesPoints {
+			return nil, fmt.Errorf("时间范围过大，最多返回 %d 个点", maxSeriesPoints)
+		}
+		clicks := buckets[t.Unix()]
+		if t.Unix() == current {
+			clicks += pending
+		}
+		result.Points = append(result.Points, SeriesPoint{Time: t, Clicks: clicks})
+		result.Total += clicks
+	}
+	return result, nil
+}
+
+func (s *FileStore) seriesPath() string {
+	return filepath.Join(filepath.Dir(s.dataPath), "click_series.json")
+}
+
+func (s *FileStore) loadSeries() error {
+	data, err := os.ReadFile(s.seriesPath())
+	if err != nil {
+		return err
+	}
+
+	series := make(map[string]*clickSeries)
+	if err := json.Unmarshal(data, &series); err != nil {
+		return fmt.Errorf("解析点击序列失败: %w", err)
+	}
+
+	s.seriesMu.Lock()
+	defer s.seriesMu.Unlock()
+	for id, cs := range series {
+		if cs.Minute == nil {
+			cs.Minute = make(map[int64]int)
+		}
+		if cs.Hour == nil {
+			cs.Hour = make(map[int64]int)
+		}
+		if cs.Day == nil {
+			cs.Day = make(map[int64]int)
+		}
+		s.series[id] = cs
+	}
+	return nil
+}
+
+func (s *FileStore) saveSeries() error {
+	s.mu.RLock()
+	s.seriesMu.Lock()
+	// 丢弃已删除文件的序列
+	for id := range s.series {
+		if _, exists := s.files[id]; !exists {
+			delete(s.series, id)
+		}
+	}
+	data, err := json.Marshal(s.series)
+	s.seriesMu.Unlock()
+	s.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("序列化点击序列失败: %w", err)
+	}
+
+	tempPath := s.seriesPath() + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("写入临时文件失败: %w", err)
+	}
+	if err := os.Rename(tempPath, s.seriesPath()); err != nil {
+		return fmt.Errorf("重命名文件失败: %w", err)
+	}
+	return nil
+}
+
+// 把多个文件的点击趋势并入into，并删除原来的记录
+func (s *FileStore) mergeSeries(into string, from []string) {
+	s.seriesMu.Lock()
+	defer s.seriesMu.Unlock()
+
+	target, ok := s.series[into]
+	for _, id := range from {
+		cs, exists := s.series[id]
+		if !exists {
+			continue
+		}
+		delete(s.series, id)
+		if !ok {
+			target = newClickSeries()
+			s.series[into] = target
+			ok = true
+		}
+		for ts, n := range cs.Minute {
+			target.Minute[ts] += n
+		}
+		for ts, n := range cs.Hour {
+			target.Hour[ts] += n
+		}
+		for ts, n := range cs.Day {
+			target.Day[ts] += n
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+// 小时桶和天桶都按本地时钟划分，半小时时区也对齐到本地整点
+func TestBucketStartLocalWallClock(t *testing.T) {
+	loc := time.FixedZone("UTC+5:30", 5*3600+1800)
+	at := time.Date(2026, 3, 1, 10, 45, 12, 0, loc)
+	tests := []struct {
+		granularity string
+		want        time.Time
+	}{
+		{GranularityMinute, time.Date(2026, 3, 1, 10, 45, 0, 0, loc)},
+		{GranularityHour, time.Date(2026, 3, 1, 10, 0, 0, 0, loc)},
+		{GranularityDay, time.Date(2026, 3, 1, 0, 0, 0, 0, loc)},
+	}
+	for _, tt := range tests {
+		// 参数带其他时区时结果相同
+		for _, in := range []time.Time{at, at.UTC()} {
+			if got := bucketStartIn(in, tt.granularity, loc); !got.Equal(tt.want) {
+				t.Errorf("bucketStartIn(%v, %s) = %v, 期望 %v", in, tt.granularity, got, tt.want)
+			}
+		}
+	}
+}
+
+// 读取序列时包含分段计数器中尚未合并的点击
+func TestGetClickSeriesIncludesPending(t *testing.T) {
+	s := newTestStore(t, "a")
+	for i := 0; i < 3; i++ {
+		if err := s.IncrementClick("a"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Now()
+	for _, g := range []string{GranularityMinute, GranularityHour, GranularityDay} {
+		series, err := s.GetClickSeries("a", now.Add(-time.Minute), now.Add(time.Minute), g)
+		if err != nil {
+			t.Fatalf("GetClickSeries(%s): %v", g, err)
+		}
+		if series.Total != 3 {
+			t.Errorf("%s 序列总数 = %d, 期望 3", g, series.Total)
+		}
+	}
+}
@@ -0,0 +1,390 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"file-ranking/internal/logger"
+)
+
+// 分段读取的默认值和上限
+const (
+	DefaultContentLength = 100 * 1024
+	MaxContentLength     = 1024 * 1024
+	DefaultContentLines  = 200
+	MaxContentLines      = 5000
+)
+
+// ContentRange 读取范围：StartLine>0时按行读取，否则按字节偏移读取
+type ContentRange struct {
+	Offset    int64
+	Length    int64
+	StartLine int
+	Lines     int
+}
+
+// FileContent 一段文件内容及文件的总体信息
+type FileContent struct {
+	Content    string `json:"content"`
+	Offset     int64  `json:"offset"`
+	Length     int64  `json:"length"`
+	NextOffset int64  `json:"next_offset"`
+	StartLine  int    `json:"start_line,omitempty"`
+	EndLine    int    `json:"end_line,omitempty"`
+	TotalSize  int64  `json:"total_size"`
+	TotalLines int    `json:"total_lines"`
+	HasMore    bool   `json:"has_more"`
+	// 文件原始编码，偏移和长度都按原始字节计算，内容已转换为UTF-8
+	Encoding string `json:"encoding"`
+	// 读取时的文件版本，保存编辑时作为 If-Match
+	Version int `json:"version"`
+}
+
+// 行数缓存，文件大小和修改时间不变时复用
+type lineCount struct {
+	size    int64
+	modTime time.Time
+	lines   int
+}
+
+var lineCounts sync.Map // path -> lineCount
+
+// 文件被删除、移走或内容被替换后清除它的行数缓存
+func forgetLineCount(path string) {
+	lineCounts.Delete(path)
+}
+
+// GetFileContent 按字节范围或行范围从磁盘读取文本内容，不会整体读入内存
+func (s *FileStore) GetFileContent(id string, r ContentRange) (*FileContent, error) {
+	log := logger.GetInstance()
+
+	s.mu.RLock()
+	file, exists := s.files[id]
+	var path, name, mimeType, enc string
+	var version int
+	if exists {
+		path, name, mimeType, enc = file.Path, file.Name, file.MimeType, file.Encoding
+		version = file.Version
+	}
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("文件不存在")
+	}
+	if !IsTextMIME(mimeType) {
+		log.Warn("⚠️ 拒绝以文本读取二进制文件: %s (%s)", name, mimeType)
+		return nil, ErrBinaryFile
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Error("文件不存在: %s", path)
+			return nil, fmt.Errorf("文件不存在")
+		}
+		return nil, fmt.Errorf("读取文件失败: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("读取文件失败: %w", err)
+	}
+
+	var content *FileContent
+	if enc == "" {
+		enc = EncodingUTF8
+	}
+	if r.StartLine > 0 {
+		content, err = readLines(f, enc, r.StartLine, r.Lines)
+	} else {
+		content, err = readRange(f, enc, info.Size(), r.Offset, r.Length)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取文件失败: %w", err)
+	}
+
+	content.Encoding = enc
+	content.Version = version
+	content.TotalSize = info.Size()
+	if content.TotalLines, err = countLines(path, enc, info); err != nil {
+		return nil, fmt.Errorf("读取文件失败: %w", err)
+	}
+	return content, nil
+}
+
+// GB18030的续字节可能与ASCII重叠，从前面最近的换行处开始重新对齐字符
+const gbResyncWindow = 4 * 1024
+
+// 按字节读取，首尾对齐到完整字符
+func readRange(f *os.File, enc string, size, offset, length int64) (*FileContent, error) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > size {
+		offset = size
+	}
+	if length <= 0 {
+		length = DefaultContentLength
+	}
+	if length > MaxContentLength {
+		length = MaxContentLength
+	}
+
+	base := offset
+	if enc == EncodingGBK || enc == EncodingGB18030 {
+		base = offset - gbResyncWindow
+		if base < 0 {
+			base = 0
+		}
+	}
+
+	// 多读几个字节用于对齐字符边界
+	buf := make([]byte, offset-base+length+4)
+	n, err := f.ReadAt(buf, base)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	buf = buf[:n]
+	atEOF := base+int64(n) >= size
+
+	// 找到偏移处或之后的第一个字符起点
+	start := 0
+	switch enc {
+	case EncodingGBK, EncodingGB18030:
+		if base > 0 {
+			if i := bytes.LastIndexByte(buf[:offset-base], '\n'); i >= 0 {
+				start = i + 1
+			}
+		}
+		for int64(start) < offset-base && start < len(buf) {
+			if n := charLen(buf[start:], enc); n > 0 {
+				start += n
+			} else {
+				start = len(buf)
+			}
+		}
+	case EncodingUTF16LE, EncodingUTF16BE:
+		if offset%2 == 1 {
+			start++
+		}
+		// 不从代理对的后半部分开始
+		if start+1 < len(buf) {
+			hi := buf[start+1]
+			if enc == EncodingUTF16BE {
+				hi = buf[start]
+			}
+			if hi >= 0xDC && hi <= 0xDF {
+				start += 2
+			}
+		}
+	default:
+		for start < len(buf) && start < utf8.UTFMax-1 && !utf8.RuneStart(buf[start]) {
+			start++
+		}
+	}
+	if start > len(buf) {
+		start = len(buf)
+	}
+
+	// 不截断末尾的多字节字符
+	end := start
+	limit := start + int(length)
+	for end < len(buf) {
+		n := charLen(buf[end:], enc)
+		if n == 0 && atEOF {
+			n = len(buf) - end // 文件末尾不完整的字符原样返回
+		}
+		if n == 0 || end+n > limit {
+			break
+		}
+		end += n
+	}
+
+	content, err := decodeText(buf[start:end], enc)
+	if err != nil {
+		return nil, err
+	}
+	result := &FileContent{
+		Content:    content,
+		Offset:     base + int64(start),
+		Length:     int64(end - start),
+		NextOffset: base + int64(end),
+	}
+	result.HasMore = result.NextOffset < size
+	return result, nil
+}
+
+// 按行读取，startLine从1开始
+func readLines(f *os.File, enc string, startLine, lines int) (*FileContent, error) {
+	if lines <= 0 {
+		lines = DefaultContentLines
+	}
+	if lines > MaxContentLines {
+		lines = MaxContentLines
+	}
+
+	reader := bufio.NewReader(f)
+	var offset int64
+	line := 1
+	// 跳过起始行之前的内容
+	for ; line < startLine; line++ {
+		n, err := readLine(reader, enc, offset, func([]byte) {})
+		offset += n
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var b bytes.Buffer
+	var consumed int64
+	read := 0
+	// 起始行超出文件末尾时返回空内容
+	if line == startLine {
+		for read < lines && b.Len() < MaxContentLength {
+			n, err := readLine(reader, enc, offset+consumed, func(chunk []byte) {
+				appendLimited(&b, chunk, enc, MaxContentLength)
+			})
+			consumed += n
+			if n > 0 {
+				read++
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	_, peekErr := reader.Peek(1)
+
+	content, err := decodeText(b.Bytes(), enc)
+	if err != nil {
+		return nil, err
+	}
+	result := &FileContent{
+		Content:    content,
+		Offset:     offset,
+		Length:     int64(b.Len()),
+		NextOffset: offset + int64(b.Len()),
+		StartLine:  startLine,
+		// 超长行被截断时剩余部分可以按字节偏移继续读取
+		HasMore: peekErr == nil || consumed > int64(b.Len()),
+	}
+	if read > 0 {
+		result.EndLine = startLine + read - 1
+	}
+	return result, nil
+}
+
+// 把chunk追加到b，总长度不超过limit且不截断字符
+func appendLimited(b *bytes.Buffer, chunk []byte, enc string, limit int) {
+	room := limit - b.Len()
+	if room <= 0 {
+		return
+	}
+	if len(chunk) > room {
+		end := 0
+		for end < room {
+			n := charLen(chunk[end:], enc)
+			if n == 0 || end+n > room {
+				break
+			}
+			end += n
+		}
+		chunk = chunk[:end]
+	}
+	b.Write(chunk)
+}
+
+// 读取一行（含换行符）的原始字节，分块交给emit，超长行也不会整行读入内存；
+// pos为该行在文件中的偏移，UTF-16据此判断0x0A是否为换行符
+func readLine(r *bufio.Reader, enc string, pos int64, emit func([]byte)) (int64, error) {
+	var n int64
+	var prev byte // 上一块的最后一个字节
+	for {
+		chunk, err := r.ReadSlice('\n')
+		n += int64(len(chunk))
+		emit(chunk)
+		if err == nil {
+			at := pos + n - 1
+			before := prev
+			if len(chunk) >= 2 {
+				before = chunk[len(chunk)-2]
+			}
+			switch enc {
+			case EncodingUTF16LE:
+				// 小端的换行是偶数位置上的 0A 00
+				if at%2 == 0 {
+					if next, peekErr := r.Peek(1); peekErr == nil && next[0] == 0 {
+						r.ReadByte()
+						emit([]byte{0})
+						return n + 1, nil
+					}
+				}
+			case EncodingUTF16BE:
+				// 大端的换行是偶数位置上的 00 0A
+				if at%2 == 1 && before == 0 {
+					return n, nil
+				}
+			default:
+				return n, nil
+			}
+			// 0x0A只是某个字符的一部分，继续读
+			prev = chunk[len(chunk)-1]
+			continue
+		}
+		if len(chunk) > 0 {
+			prev = chunk[len(chunk)-1]
+		}
+		if err != bufio.ErrBufferFull {
+			return n, err
+		}
+	}
+}
+
+// 统计行数，最后一行没有换行符也算一行
+func countLines(path, enc string, info os.FileInfo) (int, error) {
+	if v, ok := lineCounts.Load(path); ok {
+		c := v.(lineCount)
+		if c.size == info.Size() && c.modTime.Equal(info.ModTime()) {
+			return c.lines, nil
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	lines := 0
+	reader := bufio.NewReaderSize(f, 64*1024)
+	var pos int64
+	for {
+		n, err := readLine(reader, enc, pos, func([]byte) {})
+		if n > 0 {
+			lines++
+			pos += n
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	lineCounts.Store(path, lineCount{size: info.Size(), modTime: info.ModTime(), lines: lines})
+	return lines, nil
+}
@@ -0,0 +1,286 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"file-ranking/internal/logger"
+)
+
+// 上传内容与已有文件相同时的处理方式
+const (
+	DuplicateKeep    = "keep"    // 保留两份
+	DuplicateReplace = "replace" // 用新上传的文件替换已有文件，保留其ID和点击数
+	DuplicateDiscard = "discard" // 丢弃新上传的文件
+)
+
+// 上传结果
+const (
+	UploadCreated   = "created"
+	UploadKept      = "kept"
+	UploadReplaced  = "replaced"
+	UploadDiscarded = "discarded"
+)
+
+// ValidDuplicatePolicy 是否为支持的重复处理方式
+func ValidDuplicatePolicy(policy string) bool {
+	return policy == DuplicateKeep || policy == DuplicateReplace || policy == DuplicateDiscard
+}
+
+// UploadOutcome 上传结果；内容与已有文件相同时Duplicates为这些文件，
+// 替换或丢弃时File为已有的文件
+type UploadOutcome struct {
+	File       *FileData
+	Duplicates []FileData
+	Action     string
+}
+
+// DuplicateGroup 内容相同的一组文件，Files[0]为合并时保留的文件
+type DuplicateGroup struct {
+	Hash        string     `json:"hash"`
+	Files       []FileData `json:"files"`
+	TotalClicks int        `json:"total_clicks"`
+}
+
+// MergedGroup 一组重复文件的合并结果
+type MergedGroup struct {
+	Hash    string     `json:"hash"`
+	Kept    FileData   `json:"kept"`
+	Removed []FileData `json:"removed"`
+}
+
+// FindDuplicates 查找内容哈希相同的文件，点击数多的在前，相同时先上传的在前
+func (s *FileStore) FindDuplicates(hash, excludeID string) []FileData {
+	if hash == "" {
+		return nil
+	}
+	s.mu.RLock()
+	var dups []FileData
+	for id, file := range s.files {
+		if id != excludeID && file.Hash == hash {
+			dups = append(dups, s.withPending(*file))
+		}
+	}
+	s.mu.RUnlock()
+	sortDuplicates(dups)
+	return dups
+}
+
+// ListDuplicates 列出所有内容重复的文件组，总点击数多的组在前
+func (s *FileStore) ListDuplicates() []DuplicateGroup {
+	s.mu.RLock()
+	byHash := make(map[string][]FileData)
+	for _, file := range s.files {
+		if file.Hash != "" {
+			byHash[file.Hash] = append(byHash[file.Hash], s.withPending(*file))
+		}
+	}
+	s.mu.RUnlock()
+
+	groups := make([]DuplicateGroup, 0)
+	for hash, files := range byHash {
+		if len(files) < 2 {
+			continue
+		}
+		sortDuplicates(files)
+		group := DuplicateGroup{Hash: hash, Files: files}
+		for _, f := range files {
+			group.TotalClicks += f.Clicks
+		}
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].TotalClicks != groups[j].TotalClicks {
+			return groups[i].TotalClicks > groups[j].TotalClicks
+		}
+		return groups[i].Hash < groups[j].Hash
+	})
+	return groups
+}
+
+func sortDuplicates(files []FileData) {
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Clicks != files[j].Clicks {
+			return files[i].Clicks > files[j].Clicks
+		}
+		return files[i].UploadAt.Before(files[j].UploadAt)
+	})
+}
+
+// 登记已写入磁盘并通过校验的上传文件，按policy处理内容重复的情况
+func (s *FileStore) registerUpload(fileData *FileData, policy string) *UploadOutcome {
+	log := logger.GetInstance()
+
+	dups := s.FindDuplicates(fileData.Hash, fileData.ID)
+	if len(dups) == 0 {
+		s.addFile(fileData)
+		return &UploadOutcome{File: fileData, Action: UploadCreated}
+	}
+
+	switch policy {
+	case DuplicateDiscard:
+		os.Remove(fileData.Path)
+		existing := dups[0]
+		log.Info("♻️ 上传内容与已有文件相同，已丢弃: %s → %s (ID: %s)", fileData.Name, existing.Name, existing.ID)
+		return &UploadOutcome{File: &existing, Duplicates: dups, Action: UploadDiscarded}
+	case DuplicateReplace:
+		if replaced, ok := s.replaceWithUpload(dups[0].ID, fileData); ok {
+			log.Info("♻️ 上传内容与已有文件相同，已替换: %s (ID: %s)", replaced.Name, replaced.ID)
+			return &UploadOutcome{File: replaced, Duplicates: dups, Action: UploadReplaced}
+		}
+		// 已有文件刚被删除、来自导入目录或正在编辑，按新文件登记
+	}
+
+	s.addFile(fileData)
+	log.Info("♻️ 上传内容与 %d 个已有文件相同，保留两份: %s (ID: %s)", len(dups), fileData.Name, fileData.ID)
+	return &UploadOutcome{File: fileData, Duplicates: dups, Action: UploadKept}
+}
+
+// 已有文件改用新上传的文件和文件名，ID、点击数和元数据不变；内容相同所以修改时间不变。
+// 导入目录的文件以导入目录为准，正在编辑或保存内容的文件不能被替换
+func (s *FileStore) replaceWithUpload(id string, upload *FileData) (*FileData, bool) {
+	s.mu.Lock()
+	file, exists := s.files[id]
+	if !exists || file.Import != nil || s.contentWriters[id] || s.GetEditLock(id) != nil {
+		s.mu.Unlock()
+		return nil, false
+	}
+	oldPath, oldName := file.Path, file.Name
+	file.Path = upload.Path
+	file.Name = upload.Name
+	file.Size = upload.Size
+	file.MimeType = upload.MimeType
+	file.Encoding = upload.Encoding
+	file.Hash = upload.Hash
+	file.Version++
+	s.markChanged()
+	replaced := s.withPending(*file)
+	s.mu.Unlock()
+
+	os.Remove(oldPath)
+	forgetLineCount(oldPath)
+	if oldName != replaced.Name {
+		s.notify(FileRenamed, replaced)
+	}
+	return &replaced, true
+}
+
+// MergeDuplicates 把内容相同的文件合并为一个：保留点击数最多的文件，
+// 其余文件的点击数、点击趋势和标签并入其中后删除。hashes为空时合并所有重复组。
+// 来自导入目录或正在编辑的文件不会被删除，保留在原处
+func (s *FileStore) MergeDuplicates(hashes []string, reason, actor string) ([]MergedGroup, error) {
+	log := logger.GetInstance()
+	var only map[string]bool
+	if len(hashes) > 0 {
+		only = make(map[string]bool, len(hashes))
+		for _, h := range hashes {
+			only[h] = true
+		}
+	}
+
+	now := time.Now()
+	var audit []AuditEntry
+	var removedPaths []string
+	var updated []FileData
+	merged := make([]MergedGroup, 0)
+
+	s.mu.Lock()
+	byHash := make(map[string][]FileData)
+	for id, file := range s.files {
+		if file.Hash == "" || (only != nil && !only[file.Hash]) {
+			continue
+		}
+		file.Clicks += int(s.drainCounter(id))
+		byHash[file.Hash] = append(byHash[file.Hash], *file)
+	}
+
+	for hash, files := range byHash {
+		if len(files) < 2 {
+			continue
+		}
+		sortDuplicates(files)
+		keeper := s.files[files[0].ID]
+		oldClicks := keeper.Clicks
+		tags := keeper.Tags
+		group := MergedGroup{Hash: hash}
+
+		for _, dup := range files[1:] {
+			if dup.Import != nil || s.GetEditLock(dup.ID) != nil {
+				log.Warn("⚠️ 跳过来自导入目录或正在编辑的重复文件: %s (ID: %s)", dup.Name, dup.ID)
+				continue
+			}
+			if _, err := s.removeLocked(dup.ID, nil); err != nil {
+				log.Warn("⚠️ 跳过无法删除的重复文件: %s (ID: %s) - %v", dup.Name, dup.ID, err)
+				continue
+			}
+			keeper.Clicks += dup.Clicks
+			tags = mergeTags(tags, dup.Tags)
+			removedPaths = append(removedPaths, dup.Path)
+			group.Removed = append(group.Removed, dup)
+			audit = append(audit, AuditEntry{
+				Time:      now,
+				Action:    "merge",
+				FileID:    dup.ID,
+				Name:      dup.Name,
+				OldClicks: dup.Clicks,
+				NewClicks: 0,
+				Reason:    fmt.Sprintf("%s (合并到 %s)", reason, keeper.ID),
+				Actor:     actor,
+			})
+		}
+		if len(group.Removed) == 0 {
+			continue
+		}
+		// 标签切片整体替换，不修改可能被共享的原切片
+		if len(tags) != len(keeper.Tags) {
+			keeper.Tags = tags
+			updated = append(updated, *keeper)
+		}
+		audit = append(audit, AuditEntry{
+			Time:      now,
+			Action:    "merge",
+			FileID:    keeper.ID,
+			Name:      keeper.Name,
+			OldClicks: oldClicks,
+			NewClicks: keeper.Clicks,
+			Reason:    reason,
+			Actor:     actor,
+		})
+		group.Kept = *keeper
+		merged = append(merged, group)
+	}
+	if len(merged) > 0 {
+		s.markChanged()
+	}
+	s.mu.Unlock()
+
+	for _, path := range removedPaths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Warn("⚠️ 删除重复文件失败: %s - %v", path, err)
+		}
+	}
+	for _, group := range merged {
+		ids := make([]string, len(group.Removed))
+		for i, f := range group.Removed {
+			ids[i] = f.ID
+		}
+		// 先并入点击趋势，移除时不再有重复文件的趋势可删
+		s.mergeSeries(group.Kept.ID, ids)
+		for _, f := range group.Removed {
+			s.afterRemove(f, f.Path)
+		}
+		log.Info("🔗 合并重复文件: %s (ID: %s) 并入 %d 个文件，点击数 %d", group.Kept.Name, group.Kept.ID, len(group.Removed), group.Kept.Clicks)
+	}
+	for _, f := range updated {
+		s.notify(FileMetadataChanged, f)
+	}
+	s.appendAudit(audit)
+	return merged, nil
+}
+
+// 合并两组标签，与修改元数据时一样不区分大小写去重并排序
+func mergeTags(a, b []string) []string {
+	return normalizeTags(append(append([]string{}, a...), b...))
+}
@@ -0,0 +1,280 @@
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"file-ranking/internal/logger"
+)
+
+// DefaultEditLockTTL 编辑锁默认有效期，持有者需要在到期前续期
+const DefaultEditLockTTL = 2 * time.Minute
+
+// 过期的编辑锁多久清理并通知一次
+const editLockSweepInterval = 5 * time.Second
+
+var (
+	// ErrFileLocked 文件正在被其他人编辑
+	ErrFileLocked = errors.New("文件正在被其他人编辑")
+	// ErrLockNotHeld 没有持有该文件的编辑锁（锁不存在、已过期或属于其他人）
+	ErrLockNotHeld = errors.New("没有持有编辑锁或编辑锁已过期")
+)
+
+// EditLock 文件的独占编辑锁，锁只保存在内存中，重启后失效
+type EditLock struct {
+	FileID     string    `json:"file_id"`
+	Holder     string    `json:"holder"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// 持有凭证，只返回给获取锁的客户端，续期、释放和保存内容时携带
+	Token string `json:"-"`
+}
+
+// LockEvent 编辑锁变化通知，Lock为nil表示锁已释放或过期
+type LockEvent struct {
+	FileID string
+	Lock   *EditLock
+}
+
+type editLocks struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	locks map[string]*EditLock
+
+	subMu       sync.RWMutex
+	subscribers []func(LockEvent)
+}
+
+func newEditLocks() *editLocks {
+	return &editLocks{
+		ttl:   DefaultEditLockTTL,
+		locks: make(map[string]*EditLock),
+	}
+}
+
+// 文件当前有效的锁，过期的锁视为不存在，调用方需持有l.mu
+func (l *editLocks) active(id string, now time.Time) *EditLock {
+	lock, ok := l.locks[id]
+	if !ok || !now.Before(lock.ExpiresAt) {
+		return nil
+	}
+	return lock
+}
+
+// 检查写入方是否可以修改文件：没有有效的锁，或者持有该锁
+func (l *editLocks) checkWriter(id, token string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lock := l.active(id, time.Now())
+	if lock == nil || lock.Token == token {
+		return nil
+	}
+	return fmt.Errorf("%w: %s (到期时间: %s)", ErrFileLocked, lock.Holder, lock.ExpiresAt.Format("15:04:05"))
+}
+
+// 返回给持有者以外的调用方和订阅方的副本，不含持有凭证
+func (lock *EditLock) withoutToken() *EditLock {
+	current := *lock
+	current.Token = ""
+	return &current
+}
+
+func newLockToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成编辑锁凭证失败: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// SetEditLockTTL 设置编辑锁有效期
+func (s *FileStore) SetEditLockTTL(ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("编辑锁有效期必须大于0")
+	}
+	s.locks.mu.Lock()
+	s.locks.ttl = ttl
+	s.locks.mu.Unlock()
+	return nil
+}
+
+// SubscribeLocks 订阅编辑锁的获取、续期、释放和过期，回调在释放锁后同步调用，不能阻塞；
+// 事件中的锁不含持有凭证
+func (s *FileStore) SubscribeLocks(fn func(LockEvent)) {
+	s.locks.subMu.Lock()
+	s.locks.subscribers = append(s.locks.subscribers, fn)
+	s.locks.subMu.Unlock()
+}
+
+func (s *FileStore) notifyLock(id string, lock *EditLock) {
+	s.locks.subMu.RLock()
+	subscribers := s.locks.subscribers
+	s.locks.subMu.RUnlock()
+
+	for _, fn := range subscribers {
+		fn(LockEvent{FileID: id, Lock: lock})
+	}
+}
+
+// AcquireEditLock 获取文件的编辑锁；token为当前持有者的凭证时相当于续期，
+// 文件正被其他人编辑时返回 ErrFileLocked 和对方的锁
+func (s *FileStore) AcquireEditLock(id, holder, token string) (*EditLock, error) {
+	holder = strings.TrimSpace(holder)
+	if holder == "" {
+		return nil, fmt.Errorf("编辑者名称不能为空")
+	}
+	if _, exists := s.GetFile(id); !exists {
+		return nil, fmt.Errorf("文件不存在: %s", id)
+	}
+
+	l := s.locks
+	l.mu.Lock()
+	now := time.Now()
+	if lock := l.active(id, now); lock != nil {
+		if lock.Token != token {
+			current := lock.withoutToken()
+			l.mu.Unlock()
+			return current, ErrFileLocked
+		}
+		lock.Holder = holder
+		lock.ExpiresAt = now.Add(l.ttl)
+		renewed := *lock
+		l.mu.Unlock()
+		s.notifyLock(id, renewed.withoutToken())
+		return &renewed, nil
+	}
+
+	newToken, err := newLockToken()
+	if err != nil {
+		l.mu.Unlock()
+		return nil, err
+	}
+	lock := &EditLock{
+		FileID:     id,
+		Holder:     holder,
+		AcquiredAt: now,
+		ExpiresAt:  now.Add(l.ttl),
+		Token:      newToken,
+	}
+	l.locks[id] = lock
+	acquired := *lock
+	l.mu.Unlock()
+
+	logger.GetInstance().Info("🔒 获取编辑锁: %s (持有者: %s)", id, holder)
+	s.notifyLock(id, acquired.withoutToken())
+	return &acquired, nil
+}
+
+// RenewEditLock 延长编辑锁的有效期，只有持有者可以续期
+func (s *FileStore) RenewEditLock(id, token string) (*EditLock, error) {
+	l := s.locks
+	l.mu.Lock()
+	now := time.Now()
+	lock := l.active(id, now)
+	if lock == nil || lock.Token != token {
+		l.mu.Unlock()
+		return nil, ErrLockNotHeld
+	}
+	lock.ExpiresAt = now.Add(l.ttl)
+	renewed := *lock
+	l.mu.Unlock()
+
+	s.notifyLock(id, renewed.withoutToken())
+	return &renewed, nil
+}
+
+// ReleaseEditLock 释放编辑锁，只有持有者可以释放
+func (s *FileStore) ReleaseEditLock(id, token string) error {
+	l := s.locks
+	l.mu.Lock()
+	lock := l.active(id, time.Now())
+	if lock == nil || lock.Token != token {
+		l.mu.Unlock()
+		return ErrLockNotHeld
+	}
+	delete(l.locks, id)
+	l.mu.Unlock()
+
+	logger.GetInstance().Info("🔓 释放编辑锁: %s (持有者: %s)", id, lock.Holder)
+	s.notifyLock(id, nil)
+	return nil
+}
+
+// GetEditLock 文件当前有效的编辑锁（不含持有凭证），没有时返回nil
+func (s *FileStore) GetEditLock(id string) *EditLock {
+	l := s.locks
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if lock := l.active(id, time.Now()); lock != nil {
+		return lock.withoutToken()
+	}
+	return nil
+}
+
+// ListEditLocks 所有有效的编辑锁（不含持有凭证），按获取时间排序
+func (s *FileStore) ListEditLocks() []EditLock {
+	l := s.locks
+	l.mu.Lock()
+	now := time.Now()
+	locks := make([]EditLock, 0, len(l.locks))
+	for id := range l.locks {
+		if lock := l.active(id, now); lock != nil {
+			locks = append(locks, *lock.withoutToken())
+		}
+	}
+	l.mu.Unlock()
+
+	sort.Slice(locks, func(i, j int) bool { return locks[i].AcquiredAt.Before(locks[j].AcquiredAt) })
+	return locks
+}
+
+// 清理过期的锁并通知订阅方
+func (s *FileStore) pruneEditLocks(now time.Time) {
+	l := s.locks
+	l.mu.Lock()
+	var expired []*EditLock
+	for id, lock := range l.locks {
+		if !now.Before(lock.ExpiresAt) {
+			expired = append(expired, lock)
+			delete(l.locks, id)
+		}
+	}
+	l.mu.Unlock()
+
+	for _, lock := range expired {
+		logger.GetInstance().Info("⌛ 编辑锁已过期: %s (持有者: %s)", lock.FileID, lock.Holder)
+		s.notifyLock(lock.FileID, nil)
+	}
+}
+
+// 删除文件时一并释放它的编辑锁
+func (s *FileStore) dropEditLock(id string) {
+	l := s.locks
+	l.mu.Lock()
+	_, held := l.locks[id]
+	delete(l.locks, id)
+	l.mu.Unlock()
+
+	if held {
+		s.notifyLock(id, nil)
+	}
+}
+
+func (s *FileStore) editLockSweeper() {
+	ticker := time.NewTicker(editLockSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			s.pruneEditLocks(now)
+		case <-s.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEditLockLifecycle(t *testing.T) {
+	s := newTestStore(t, "a")
+
+	owner, err := s.AcquireEditLock("a", "alice", "")
+	if err != nil {
+		t.Fatalf("AcquireEditLock: %v", err)
+	}
+	if owner.Token == "" || owner.Holder != "alice" {
+		t.Fatalf("持有者拿到的锁 = %+v", owner)
+	}
+
+	tests := []struct {
+		name string
+		op   func() error
+		want error
+	}{
+		{"其他人获取", func() error { _, err := s.AcquireEditLock("a", "bob", ""); return err }, ErrFileLocked},
+		{"其他人用错误凭证获取", func() error { _, err := s.AcquireEditLock("a", "bob", "bad"); return err }, ErrFileLocked},
+		{"其他人续期", func() error { _, err := s.RenewEditLock("a", "bad"); return err }, ErrLockNotHeld},
+		{"其他人释放", func() error { return s.ReleaseEditLock("a", "bad") }, ErrLockNotHeld},
+		{"其他人写入", func() error { return s.locks.checkWriter("a", "") }, ErrFileLocked},
+		{"持有者写入", func() error { return s.locks.checkWriter("a", owner.Token) }, nil},
+		{"持有者再次获取即续期", func() error { _, err := s.AcquireEditLock("a", "alice", owner.Token); return err }, nil},
+		{"持有者续期", func() error { _, err := s.RenewEditLock("a", owner.Token); return err }, nil},
+		{"持有者释放", func() error { return s.ReleaseEditLock("a", owner.Token) }, nil},
+		{"释放后再释放", func() error { return s.ReleaseEditLock("a", owner.Token) }, ErrLockNotHeld},
+		{"释放后其他人写入", func() error { return s.locks.checkWriter("a", "") }, nil},
+	}
+	for _, tt := range tests {
+		err := tt.op()
+		switch {
+		case tt.want == nil && err != nil:
+			t.Errorf("%s: err = %v, 期望成功", tt.name, err)
+		case tt.want != nil && !errors.Is(err, tt.want):
+			t.Errorf("%s: err = %v, 期望 %v", tt.name, err, tt.want)
+		}
+	}
+
+	if _, err := s.AcquireEditLock("nope", "bob", ""); err == nil {
+		t.Error("不存在的文件不能获取编辑锁")
+	}
+}
+
+// 凭证只返回给持有者，查询、列表、冲突返回和订阅事件中都不含凭证
+func TestEditLockTokenOnlyForHolder(t *testing.T) {
+	s := newTestStore(t, "a")
+	events := make(chan LockEvent, 10)
+	s.SubscribeLocks(func(ev LockEvent) { events <- ev })
+
+	owner, err := s.AcquireEditLock("a", "alice", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.RenewEditLock("a", owner.Token); err != nil {
+		t.Fatal(err)
+	}
+	other, err := s.AcquireEditLock("a", "bob", "")
+	if !errors.Is(err, ErrFileLocked) {
+		t.Fatalf("err = %v, 期望 ErrFileLocked", err)
+	}
+
+	if other.Token != "" || other.Holder != "alice" {
+		t.Errorf("冲突时返回的锁 = %+v, 期望持有者 alice 且不含凭证", other)
+	}
+	if lock := s.GetEditLock("a"); lock == nil || lock.Token != "" {
+		t.Errorf("GetEditLock = %+v", lock)
+	}
+	if locks := s.ListEditLocks(); len(locks) != 1 || locks[0].Token != "" {
+		t.Errorf("ListEditLocks = %+v", locks)
+	}
+	for i := 0; i < 2; i++ {
+		if ev := <-events; ev.Lock == nil || ev.Lock.Token != "" {
+			t.Errorf("事件 %d = %+v, 不应含凭证", i, ev.Lock)
+		}
+	}
+}
+
+func TestEditLockExpires(t *testing.T) {
+	s := newTestStore(t, "a")
+	if err := s.SetEditLockTTL(50 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	events := make(chan LockEvent, 10)
+	s.SubscribeLocks(func(ev LockEvent) { events <- ev })
+
+	owner, err := s.AcquireEditLock("a", "alice", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-events
+	time.Sleep(80 * time.Millisecond)
+
+	// 过期的锁视为不存在，其他人可以写入和获取，原持有者不能续期
+	if lock := s.GetEditLock("a"); lock != nil {
+		t.Errorf("过期后 GetEditLock = %+v", lock)
+	}
+	if err := s.locks.checkWriter("a", ""); err != nil {
+		t.Errorf("过期后写入: %v", err)
+	}
+	if _, err := s.RenewEditLock("a", owner.Token); !errors.Is(err, ErrLockNotHeld) {
+		t.Errorf("过期后续期: err = %v, 期望 ErrLockNotHeld", err)
+	}
+
+	s.pruneEditLocks(time.Now())
+	select {
+	case ev := <-events:
+		if ev.FileID != "a" || ev.Lock != nil {
+			t.Errorf("过期事件 = %+v", ev)
+		}
+	default:
+		t.Error("清理过期锁时应通知订阅方")
+	}
+
+	if _, err := s.AcquireEditLock("a", "bob", ""); err != nil {
+		t.Errorf("过期后其他人获取: %v", err)
+	}
+}
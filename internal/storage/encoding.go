@@ -0,0 +1,319 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// 支持的文本编码
+const (
+	EncodingUTF8    = "utf-8"
+	EncodingUTF8BOM = "utf-8-bom"
+	EncodingUTF16LE = "utf-16le"
+	EncodingUTF16BE = "utf-16be"
+	EncodingGBK     = "gbk"
+	EncodingGB18030 = "gb18030"
+)
+
+// 识别编码时读取的文件头部长度
+const encodingSniffLen = 8 * 1024
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// ValidEncoding 是否为支持的编码
+func ValidEncoding(enc string) bool {
+	switch enc {
+	case EncodingUTF8, EncodingUTF8BOM, EncodingUTF16LE, EncodingUTF16BE, EncodingGBK, EncodingGB18030:
+		return true
+	}
+	return false
+}
+
+// 解码时跳过BOM；编码时是否写入BOM由encodeText决定
+func textEncoding(enc string) encoding.Encoding {
+	switch enc {
+	case EncodingUTF16LE:
+		return unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)
+	case EncodingUTF16BE:
+		return unicode.UTF16(unicode.BigEndian, unicode.UseBOM)
+	case EncodingGBK:
+		return simplifiedchinese.GBK
+	case EncodingGB18030:
+		return simplifiedchinese.GB18030
+	}
+	return nil
+}
+
+// 识别文件的文本编码，不是文本时返回空字符串
+func detectEncoding(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	head := make([]byte, encodingSniffLen)
+	n, _ := io.ReadFull(f, head)
+	enc := sniffEncoding(head[:n], n < encodingSniffLen)
+	if enc != EncodingUTF8 || n < encodingSniffLen {
+		return enc
+	}
+	// GBK等文件可能有很长的纯ASCII开头，开头是UTF-8时继续检查其余部分
+	_, partial := checkUTF8(head, false)
+	return scanUTF8(f, head[n-partial:])
+}
+
+// 逐块检查开头之后的内容，遇到不合法的UTF-8时从该块重新识别编码；
+// carry为上一块末尾被切开的字符
+func scanUTF8(r io.Reader, carry []byte) string {
+	buf := make([]byte, encodingSniffLen)
+	for {
+		n, err := io.ReadFull(r, buf)
+		chunk := append(append([]byte{}, carry...), buf[:n]...)
+		complete := err != nil
+		if bytes.IndexByte(chunk, 0) >= 0 {
+			return ""
+		}
+		ok, partial := checkUTF8(chunk, complete)
+		if !ok {
+			return sniffGB(chunk, complete)
+		}
+		if complete {
+			return EncodingUTF8
+		}
+		carry = chunk[len(chunk)-partial:]
+	}
+}
+
+// 检查p是否为合法的UTF-8；complete为false时p末尾被切开的字符不算错误，返回它的字节数
+func checkUTF8(p []byte, complete bool) (ok bool, partial int) {
+	valid := p
+	if !complete {
+		for i := 0; i < utf8.UTFMax-1 && len(valid) > 0 && !utf8.Valid(valid); i++ {
+			valid = valid[:len(valid)-1]
+		}
+	}
+	if !utf8.Valid(valid) {
+		return false, 0
+	}
+	return true, len(p) - len(valid)
+}
+
+// complete为false时head只是文件开头，末尾可能切开了一个字符
+func sniffEncoding(head []byte, complete bool) string {
+	switch {
+	case bytes.HasPrefix(head, utf8BOM):
+		return EncodingUTF8BOM
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}):
+		return EncodingUTF16LE
+	case bytes.HasPrefix(head, []byte{0xFE, 0xFF}):
+		return EncodingUTF16BE
+	}
+
+	if enc := sniffUTF16(head); enc != "" {
+		return enc
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return ""
+	}
+
+	if ok, _ := checkUTF8(head, complete); ok {
+		return EncodingUTF8
+	}
+	return sniffGB(head, complete)
+}
+
+// 没有BOM的UTF-16：英文字符的高字节为0，按0出现在奇数还是偶数位置判断字节序
+func sniffUTF16(head []byte) string {
+	if len(head) < 4 {
+		return ""
+	}
+	var even, odd int
+	for i, b := range head {
+		if b == 0 {
+			if i%2 == 0 {
+				even++
+			} else {
+				odd++
+			}
+		}
+	}
+	half := len(head) / 2
+	switch {
+	case odd > half*3/10 && even < half/20:
+		return EncodingUTF16LE
+	case even > half*3/10 && odd < half/20:
+		return EncodingUTF16BE
+	}
+	return ""
+}
+
+// 按GB18030的编码规则逐字符校验，出现四字节字符时为GB18030，否则为GBK
+func sniffGB(head []byte, complete bool) string {
+	fourByte := false
+	for i := 0; i < len(head); {
+		n := gbCharLen(head[i:])
+		if n == 0 {
+			if !complete && len(head)-i < 4 {
+				break
+			}
+			return ""
+		}
+		if n == 4 {
+			fourByte = true
+		}
+		i += n
+	}
+	if fourByte {
+		return EncodingGB18030
+	}
+	return EncodingGBK
+}
+
+// GB18030字符的字节数，不合法或不完整时返回0
+func gbCharLen(p []byte) int {
+	b := p[0]
+	switch {
+	case b < 0x80:
+		return 1
+	case b == 0x80 || b == 0xFF || len(p) < 2:
+		return 0
+	case p[1] >= 0x30 && p[1] <= 0x39:
+		if len(p) < 4 || p[2] < 0x81 || p[2] > 0xFE || p[3] < 0x30 || p[3] > 0x39 {
+			return 0
+		}
+		return 4
+	case p[1] >= 0x40 && p[1] <= 0xFE && p[1] != 0x7F:
+		return 2
+	}
+	return 0
+}
+
+// 识别出非UTF-8编码的纯文本时，类型中带上实际编码
+func mimeWithEncoding(mimeType, enc string) string {
+	if enc == "" || enc == EncodingUTF8 || enc == EncodingUTF8BOM {
+		return mimeType
+	}
+	mediaType, _, _ := mime.ParseMediaType(mimeType)
+	if mediaType == "text/plain" || mediaType == defaultMIME {
+		return "text/plain; charset=" + enc
+	}
+	return mimeType
+}
+
+// 识别文件类型和编码
+func detectFileType(path string) (mimeType, enc string) {
+	enc = detectEncoding(path)
+	return mimeWithEncoding(detectMIME(path), enc), enc
+}
+
+// 把一段原始字节转换为UTF-8
+func decodeText(data []byte, enc string) (string, error) {
+	switch enc {
+	case "", EncodingUTF8:
+		return string(data), nil
+	case EncodingUTF8BOM:
+		return string(bytes.TrimPrefix(data, utf8BOM)), nil
+	}
+	out, err := textEncoding(enc).NewDecoder().Bytes(data)
+	if err != nil {
+		return "", fmt.Errorf("按 %s 解码失败: %w", enc, err)
+	}
+	return string(out), nil
+}
+
+// 把UTF-8文本转换为指定编码，包含无法表示的字符时返回错误；
+// bom只对UTF-16有效，表示是否在开头写入BOM
+func encodeText(content, enc string, bom bool) ([]byte, error) {
+	e := textEncoding(enc)
+	switch enc {
+	case "", EncodingUTF8:
+		return []byte(content), nil
+	case EncodingUTF8BOM:
+		return append(append([]byte{}, utf8BOM...), content...), nil
+	case EncodingUTF16LE:
+		if !bom {
+			e = unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+		}
+	case EncodingUTF16BE:
+		if !bom {
+			e = unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+		}
+	}
+	out, err := e.NewEncoder().Bytes([]byte(content))
+	if err != nil {
+		return nil, fmt.Errorf("内容包含无法用 %s 表示的字符", enc)
+	}
+	return out, nil
+}
+
+// UTF-16文件开头是否带BOM
+func hasUTF16BOM(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(f, head); err != nil {
+		return false
+	}
+	return bytes.Equal(head, []byte{0xFF, 0xFE}) || bytes.Equal(head, []byte{0xFE, 0xFF})
+}
+
+// NewTextReader 将文件内容转换为UTF-8读取
+func NewTextReader(r io.Reader, enc string) io.Reader {
+	switch enc {
+	case "", EncodingUTF8:
+		return r
+	case EncodingUTF8BOM:
+		return transform.NewReader(r, unicode.BOMOverride(transform.Nop))
+	}
+	return transform.NewReader(r, textEncoding(enc).NewDecoder())
+}
+
+// 编码中一个字符在p开头占用的字节数，不完整时返回0
+func charLen(p []byte, enc string) int {
+	if len(p) == 0 {
+		return 0
+	}
+	switch enc {
+	case EncodingGBK, EncodingGB18030:
+		if n := gbCharLen(p); n > 0 {
+			return n
+		}
+		if len(p) < 4 && p[0] >= 0x81 && p[0] <= 0xFE {
+			return 0
+		}
+		return 1 // 非法字节单独作为一个字符
+	case EncodingUTF16LE, EncodingUTF16BE:
+		if len(p) < 2 {
+			return 0
+		}
+		unit := uint16(p[0]) | uint16(p[1])<<8
+		if enc == EncodingUTF16BE {
+			unit = uint16(p[0])<<8 | uint16(p[1])
+		}
+		if unit >= 0xD800 && unit < 0xDC00 {
+			if len(p) < 4 {
+				return 0
+			}
+			return 4
+		}
+		return 2
+	}
+	if !utf8.FullRune(p) {
+		return 0
+	}
+	_, n := utf8.DecodeRune(p)
+	return n
+}
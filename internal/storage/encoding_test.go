@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func TestDetectEncodingBeyondHead(t *testing.T) {
+	gbk, err := simplifiedchinese.GBK.NewEncoder().Bytes([]byte("中文内容"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 多字节字符跨过开头和后续块的边界
+	utf8Text := strings.Repeat("a", encodingSniffLen-1) + strings.Repeat("中", encodingSniffLen)
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"开头很长的纯ASCII的GBK文件", append([]byte(strings.Repeat("header\n", 3*encodingSniffLen/7)), gbk...), EncodingGBK},
+		{"跨块的UTF-8文件", []byte(utf8Text), EncodingUTF8},
+		{"开头之后出现0字节", append([]byte(strings.Repeat("a", 2*encodingSniffLen)), 0, 1, 2), ""},
+		{"短GBK文件", gbk, EncodingGBK},
+	}
+	dir := t.TempDir()
+	for i, tt := range tests {
+		path := filepath.Join(dir, string(rune('a'+i))+".txt")
+		if err := os.WriteFile(path, tt.data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got := detectEncoding(path); got != tt.want {
+			t.Errorf("%s: detectEncoding = %q, 期望 %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// 按原编码写回UTF-16文件时保持原文件是否带BOM
+func TestUpdateContentKeepsUTF16BOM(t *testing.T) {
+	tests := []struct {
+		name     string
+		original []byte
+		enc      string
+		want     []byte
+	}{
+		{"UTF-16LE带BOM", []byte{0xFF, 0xFE, 'a', 0}, EncodingUTF16LE, []byte{0xFF, 0xFE, 'o', 0, 'k', 0}},
+		{"UTF-16LE不带BOM", []byte{'a', 0, 'b', 0}, EncodingUTF16LE, []byte{'o', 0, 'k', 0}},
+		{"UTF-16BE带BOM", []byte{0xFE, 0xFF, 0, 'a'}, EncodingUTF16BE, []byte{0xFE, 0xFF, 0, 'o', 0, 'k'}},
+		{"UTF-16BE不带BOM", []byte{0, 'a', 0, 'b'}, EncodingUTF16BE, []byte{0, 'o', 0, 'k'}},
+	}
+	s := newTestStore(t)
+	for i, tt := range tests {
+		id := string(rune('a' + i))
+		path := filepath.Join(s.uploadDir, id+".txt")
+		if err := os.WriteFile(path, tt.original, 0644); err != nil {
+			t.Fatal(err)
+		}
+		s.mu.Lock()
+		s.files[id] = &FileData{ID: id, Name: id + ".txt", Path: path, Encoding: tt.enc}
+		s.mu.Unlock()
+
+		if err := s.UpdateFileContent(id, "ok", "", AnyVersion, ""); err != nil {
+			t.Fatalf("%s: UpdateFileContent: %v", tt.name, err)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: 写回内容 = % x, 期望 % x", tt.name, got, tt.want)
+		}
+	}
+
+	// 从其他编码转换为UTF-16时带BOM
+	data, err := encodeText("ok", EncodingUTF16LE, true)
+	if err != nil || !bytes.HasPrefix(data, []byte{0xFF, 0xFE}) {
+		t.Errorf("encodeText = % x, %v", data, err)
+	}
+}
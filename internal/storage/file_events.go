@@ -0,0 +1,34 @@
+package storage
+
+// 文件变更类型
+const (
+	FileCreated         = "created"
+	FileRenamed         = "renamed"
+	FileContentChanged  = "content"
+	FileMetadataChanged = "metadata"
+	FileRemoved         = "removed"
+)
+
+// FileEvent 文件变更通知，File为变更后的快照，删除时为删除前的快照
+type FileEvent struct {
+	Type string
+	File FileData
+}
+
+// Subscribe 订阅文件变更，回调在变更完成且释放锁后同步调用，
+// 订阅方应尽快返回，耗时处理放到自己的goroutine中
+func (s *FileStore) Subscribe(fn func(FileEvent)) {
+	s.subMu.Lock()
+	s.subscribers = append(s.subscribers, fn)
+	s.subMu.Unlock()
+}
+
+func (s *FileStore) notify(eventType string, file FileData) {
+	s.subMu.RLock()
+	subscribers := s.subscribers
+	s.subMu.RUnlock()
+
+	for _, fn := range subscribers {
+		fn(FileEvent{Type: eventType, File: file})
+	}
+}
@@ -2,6 +2,8 @@ package storage
 
 import (
 	"container/heap"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -13,6 +15,7 @@ import (
 	"time"
 
 	"file-ranking/internal/logger"
+	"file-ranking/internal/validation"
 )
 
 type FileData struct {
@@ -22,6 +25,35 @@ type FileData struct {
 	Size     int64     `json:"size"`
 	UploadAt time.Time `json:"upload_at"`
 	Path     string    `json:"path"`
+
+	// 标签、描述和自定义元数据，修改时整体替换，不在原对象上修改
+	Tags        []string          `json:"tags,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+
+	// 所在文件夹，空表示根目录
+	FolderID string `json:"folder_id,omitempty"`
+
+	// 根据文件内容识别的类型和文本编码，编码为空表示不是文本
+	MimeType string `json:"mime_type,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+
+	// 内容最后修改时间和SHA-256，只随内容变化，用于Last-Modified和ETag
+	ModifiedAt time.Time `json:"modified_at"`
+	Hash       string    `json:"hash,omitempty"`
+
+	// 内容版本号，新建时为1，内容每变化一次加1
+	Revision int `json:"revision"`
+
+	// 到期时间，到期后由清理任务按清理策略删除或归档，为空表示不过期
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// 文件版本号，名称或内容每次变化都加1，元数据、所在文件夹和点击数变化不影响，
+	// 作为ETag用于并发编辑检查
+	Version int `json:"version"`
+
+	// 从导入目录同步的文件记录其来源，用于发现修改和删除
+	Import *ImportSource `json:"import,omitempty"`
 }
 
 type FileStore struct {
@@ -45,6 +77,53 @@ type FileStore struct {
 	// 批量操作优化
 	batchChan chan batchOperation
 	batchSize int
+
+	// 点击计数，不占用元数据锁
+	counters    sync.Map // id -> *stripedCounter
+	clickSignal chan struct{}
+	done        chan struct{}
+
+	pipelineStats  pipelineStats
+	pipelineExited chan struct{}
+	autoSaveExited chan struct{}
+
+	// 审计日志
+	auditMu sync.Mutex
+
+	// 点击时间序列
+	series       map[string]*clickSeries
+	seriesMu     sync.Mutex
+	seriesPruned time.Time
+	// 开始记录点击趋势的时间，加载后不再改变
+	seriesSince time.Time
+
+	// 点击事件日志
+	events *clickEventLog
+
+	// 文件变更订阅
+	subscribers []func(FileEvent)
+	subMu       sync.RWMutex
+
+	// 文件夹
+	folders map[string]*Folder
+
+	// 未完成的分片上传
+	uploads *uploadSessions
+
+	// 上传校验链
+	validator validation.Validator
+
+	// 导入目录同步，未配置时为nil
+	importer *importer
+
+	// 编辑锁
+	locks *editLocks
+
+	// 正在写入内容的文件，写入完成前同一文件的其他写请求按版本冲突处理
+	contentWriters map[string]bool
+
+	// 清理策略
+	cleanup cleanupState
 }
 
 func NewFileStore(dataPath, uploadDir string) (*FileStore, error) {
@@ -62,7 +141,19 @@ func NewFileStore(dataPath, uploadDir string) (*FileStore, error) {
 		updateChan: make(chan struct{}, 100),
 		minHeap:    &minHeap{},
 		batchChan:  make(chan batchOperation, 1000),
-		batchSize:  10,
+		batchSize:  100,
+		clickSignal: make(chan struct{}, 1),
+		done:        make(chan struct{}),
+		pipelineExited: make(chan struct{}),
+		autoSaveExited: make(chan struct{}),
+		series:         make(map[string]*clickSeries),
+		seriesSince:    time.Now(),
+		folders:        make(map[string]*Folder),
+		events:         newClickEventLog(filepath.Join(filepath.Dir(dataPath), "click_events")),
+		validator:      defaultUploadValidator(),
+		locks:          newEditLocks(),
+		contentWriters: make(map[string]bool),
+		cleanup:        cleanupState{policy: defaultCleanupPolicy()},
 		filePool: sync.Pool{
 			New: func() interface{} {
 				return &FileData{}
@@ -70,6 +161,12 @@ func NewFileStore(dataPath, uploadDir string) (*FileStore, error) {
 		},
 	}
 
+	uploads, err := newUploadSessions(filepath.Join(uploadDir, ".sessions"))
+	if err != nil {
+		return nil, err
+	}
+	store.uploads = uploads
+
 	if err := store.load(); err != nil {
 		if !os.IsNotExist(err) {
 			return nil, fmt.Errorf("加载数据失败: %w", err)
@@ -79,8 +176,20 @@ func NewFileStore(dataPath, uploadDir string) (*FileStore, error) {
 		log.Printf("📊 成功加载 %d 个文件", len(store.files))
 	}
 
+	if err := store.loadSeries(); err != nil && !os.IsNotExist(err) {
+		log.Printf("⚠️ 加载点击序列失败: %v", err)
+	}
+
+	if err := store.loadCleanupPolicy(); err != nil {
+		log.Printf("⚠️ %v，使用默认清理策略", err)
+	}
+
 	store.buildRankedCache()
 	go store.autoSave()
+	go store.clickPipeline()
+	go store.eventWriter()
+	go store.uploadSessionGC()
+	go store.editLockSweeper()
 	log.Println("✅ 文件存储初始化完成")
 	return store, nil
 }
@@ -91,37 +200,121 @@ func (s *FileStore) load() error {
 		return err
 	}
 
-	var files []FileData
-	if err := json.Unmarshal(data, &files); err != nil {
-		return fmt.Errorf("解析JSON失败: %w", err)
+	snap, err := decodeSnapshot(data)
+	if err != nil {
+		return err
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	for _, file := range files {
-		s.files[file.ID] = &FileData{
-			ID:       file.ID,
-			Name:     file.Name,
-			Clicks:   file.Clicks,
-			Size:     file.Size,
-			UploadAt: file.UploadAt,
-			Path:     file.Path,
+	// 直接使用解析出的完整记录，新增字段无需在这里逐个复制
+	for i := range snap.Files {
+		file := &snap.Files[i]
+		file.Tags = normalizeTags(file.Tags)
+		s.files[file.ID] = file
+		s.counters.Store(file.ID, &stripedCounter{})
+	}
+
+	// 旧数据没有类型或编码信息，启动时补充识别
+	detected := 0
+	for _, file := range s.files {
+		if file.MimeType == "" || (file.Encoding == "" && (IsTextMIME(file.MimeType) || file.MimeType == defaultMIME)) {
+			mimeType, enc := detectFileType(file.Path)
+			if mimeType != file.MimeType || enc != file.Encoding {
+				file.MimeType, file.Encoding = mimeType, enc
+				detected++
+			}
+		}
+	}
+	if detected > 0 {
+		log.Printf("🔍 补充识别 %d 个文件的类型", detected)
+		s.dirty = true
+	}
+
+	// 旧数据的编辑会覆盖上传时间，此时上传时间就是最后修改时间
+	hashed := 0
+	for _, file := range s.files {
+		if file.ModifiedAt.IsZero() {
+			file.ModifiedAt = file.UploadAt
+			s.dirty = true
+		}
+		if file.Revision == 0 {
+			file.Revision = 1
+			s.dirty = true
+		}
+		if file.Version == 0 {
+			file.Version = 1
+			s.dirty = true
+		}
+		if file.Hash == "" {
+			if hash, err := hashFile(file.Path); err == nil {
+				file.Hash = hash
+				hashed++
+			}
+		}
+	}
+	if hashed > 0 {
+		log.Printf("🔍 补充计算 %d 个文件的哈希", hashed)
+		s.dirty = true
+	}
+
+	for _, folder := range snap.Folders {
+		f := folder
+		s.folders[f.ID] = &f
+	}
+	// 所在文件夹已不存在的文件放回根目录
+	for _, file := range s.files {
+		if !s.folderExists(file.FolderID) {
+			file.FolderID = RootFolderID
 		}
 	}
 
+	// 没有记录时从现在开始算，之前的点击没有时间信息
+	if snap.SeriesSince != nil {
+		s.seriesSince = *snap.SeriesSince
+	} else {
+		s.dirty = true
+	}
+
+	// 旧格式数据在下次保存时写成新格式
+	if snap.Version < snapshotVersion {
+		log.Printf("📦 数据格式从 v%d 迁移到 v%d", snap.Version, snapshotVersion)
+		s.dirty = true
+	}
+
 	return nil
 }
 
 func (s *FileStore) save() error {
-	s.mu.RLock()
+	s.foldClicks()
+
+	// 取快照时清除修改标记，写入期间的修改会重新标记，写入失败时恢复标记
+	s.mu.Lock()
 	files := make([]FileData, 0, len(s.files))
 	for _, file := range s.files {
 		files = append(files, *file)
 	}
-	s.mu.RUnlock()
+	folders := make([]Folder, 0, len(s.folders))
+	for _, folder := range s.folders {
+		folders = append(folders, *folder)
+	}
+	since := s.seriesSince
+	s.dirty = false
+	s.mu.Unlock()
+
+	if err := s.writeSnapshot(files, folders, since); err != nil {
+		s.mu.Lock()
+		s.dirty = true
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
 
-	data, err := json.MarshalIndent(files, "", "  ")
+// 将快照写入数据文件
+func (s *FileStore) writeSnapshot(files []FileData, folders []Folder, since time.Time) error {
+	data, err := json.MarshalIndent(snapshot{Version: snapshotVersion, Files: files, Folders: folders, SeriesSince: &since}, "", "  ")
 	if err != nil {
 		return fmt.Errorf("序列化JSON失败: %w", err)
 	}
@@ -135,24 +328,32 @@ func (s *FileStore) save() error {
 		return fmt.Errorf("重命名文件失败: %w", err)
 	}
 
-	s.dirty = false
-	return nil
+	return s.saveSeries()
+}
+
+func (s *FileStore) isDirty() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.dirty
 }
 
 func (s *FileStore) autoSave() {
+	defer close(s.autoSaveExited)
 	ticker := time.NewTicker(5 * time.Second)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-s.saveChan:
-			if s.dirty {
+			if s.isDirty() {
 				_ = s.save()
 			}
 		case <-ticker.C:
-			if s.dirty {
+			if s.isDirty() {
 				_ = s.save()
 			}
+		case <-s.done:
+			return
 		}
 	}
 }
@@ -165,6 +366,15 @@ func (s *FileStore) triggerSave() {
 }
 
 func (s *FileStore) UploadFile(name string, content io.Reader) (*FileData, error) {
+	outcome, err := s.UploadFileWithPolicy(name, content, DuplicateKeep)
+	if err != nil {
+		return nil, err
+	}
+	return outcome.File, nil
+}
+
+// UploadFileWithPolicy 上传文件，内容与已有文件相同时按policy处理
+func (s *FileStore) UploadFileWithPolicy(name string, content io.Reader, policy string) (*UploadOutcome, error) {
 	log := logger.GetInstance()
 	id := generateID()
 	safeName := sanitizeFilename(name)
@@ -180,118 +390,184 @@ func (s *FileStore) UploadFile(name string, content io.Reader) (*FileData, error
 	}
 	defer file.Close()
 
-	size, err := io.Copy(file, content)
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hasher), content)
 	if err != nil {
 		os.Remove(fullPath) // 清理失败文件
 		log.Error("❌ 写入文件失败: %v", err)
 		return nil, fmt.Errorf("写入文件失败: %w", err)
 	}
 
+	mimeType, enc := detectFileType(fullPath)
+	now := time.Now()
 	fileData := &FileData{
-		ID:       id,
-		Name:     name,
-		Clicks:   0,
-		Size:     size,
-		UploadAt: time.Now(),
-		Path:     fullPath,
-	}
+		ID:         id,
+		Name:       name,
+		Clicks:     0,
+		Size:       size,
+		UploadAt:   now,
+		Path:       fullPath,
+		MimeType:   mimeType,
+		Encoding:   enc,
+		ModifiedAt: now,
+		Hash:       hex.EncodeToString(hasher.Sum(nil)),
+	}
+	if err := s.validateStored(fileData, false); err != nil {
+		file.Close()
+		os.Remove(fullPath)
+		return nil, err
+	}
+
+	file.Close()
+	outcome := s.registerUpload(fileData, policy)
+	log.Info("✅ 文件上传成功: %s (ID: %s, 大小: %d bytes)", name, outcome.File.ID, size)
+	return outcome, nil
+}
 
+// 登记新文件并通知保存、订阅者和排行榜更新
+func (s *FileStore) addFile(fileData *FileData) {
+	if fileData.Revision == 0 {
+		fileData.Revision = 1
+	}
+	if fileData.Version == 0 {
+		fileData.Version = 1
+	}
 	s.mu.Lock()
-	s.files[id] = fileData
+	s.files[fileData.ID] = fileData
+	s.counters.Store(fileData.ID, &stripedCounter{})
 	s.dirty = true
 	s.cacheValid = false // 缓存失效
 	s.mu.Unlock()
 
-	log.Info("✅ 文件上传成功: %s (ID: %s, 大小: %d bytes)", name, id, size)
-	
 	s.triggerSave()
+	s.notify(FileCreated, *fileData)
 	
 	// 异步通知更新
 	select {
 	case s.updateChan <- struct{}{}:
 	default:
 	}
-	
-	return fileData, nil
 }
 
+// 点击只更新分段计数器，由clickPipeline异步合并到排行榜
 func (s *FileStore) IncrementClick(id string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	file, exists := s.files[id]
+	v, exists := s.counters.Load(id)
 	if !exists {
-		log.Printf("⚠️ 尝试点击不存在的文件: %s", id)
 		return fmt.Errorf("文件不存在: %s", id)
 	}
+	v.(*stripedCounter).add(1)
 
-	oldClicks := file.Clicks
-	file.Clicks++
-	s.dirty = true
-	s.cacheValid = false // 缓存失效
-	s.triggerSave()
-	
-	// 异步通知更新
 	select {
-	case s.updateChan <- struct{}{}:
+	case s.clickSignal <- struct{}{}:
 	default:
 	}
-	
-	log.Printf("👆 文件点击增加: %s (从 %d 到 %d)", file.Name, oldClicks, file.Clicks)
 	return nil
 }
 
-func (s *FileStore) RemoveFile(id string) error {
+// 叠加尚未合并的点击，调用方需持有读锁
+func (s *FileStore) withPending(file FileData) FileData {
+	if v, ok := s.counters.Load(file.ID); ok {
+		file.Clicks += int(v.(*stripedCounter).pending())
+	}
+	return file
+}
+
+// RemoveFile 删除文件；version不是AnyVersion时必须与文件当前版本一致
+func (s *FileStore) RemoveFile(id string, version int) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 
 	file, exists := s.files[id]
 	if !exists {
+		s.mu.Unlock()
 		log.Printf("⚠️ 尝试删除不存在的文件: %s", id)
 		return fmt.Errorf("文件不存在: %s", id)
 	}
+	if err := checkVersion(file, version); err != nil {
+		s.mu.Unlock()
+		return err
+	}
 
 	log.Printf("🗑️ 开始删除文件: %s (ID: %s)", file.Name, id)
 	
 	// 删除物理文件
-	if err := os.Remove(file.Path); err != nil && !os.IsNotExist(err) {
-		log.Printf("❌ 删除文件失败: %v", err)
-		return fmt.Errorf("删除文件失败: %w", err)
+	removed, err := s.removeLocked(id, func(file *FileData) error {
+		if err := os.Remove(file.Path); err != nil && !os.IsNotExist(err) {
+			log.Printf("❌ 删除文件失败: %v", err)
+			return fmt.Errorf("删除文件失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		s.mu.Unlock()
+		return err
 	}
+	log.Printf("✅ 文件删除成功: %s (剩余文件: %d)", removed.Name, len(s.files))
+	s.mu.Unlock()
 
-	delete(s.files, id)
-	s.dirty = true
-	s.cacheValid = false // 缓存失效
-	s.triggerSave()
-	
-	// 异步通知更新
-	select {
-	case s.updateChan <- struct{}{}:
-	default:
-	}
-	
-	log.Printf("✅ 文件删除成功: %s (剩余文件: %d)", file.Name, len(s.files))
+	s.afterRemove(removed, removed.Path)
 	return nil
 }
 
-func (s *FileStore) RenameFile(id string, newName string) error {
+// 从存储中移除文件，调用方需持有写锁。正在保存内容的文件不能移除；
+// detach不为nil时先处理磁盘上的文件，失败则文件保持不变
+func (s *FileStore) removeLocked(id string, detach func(file *FileData) error) (FileData, error) {
+	file, exists := s.files[id]
+	if !exists {
+		return FileData{}, fmt.Errorf("文件不存在: %s", id)
+	}
+	if s.contentWriters[id] {
+		return FileData{}, fmt.Errorf("%w (文件正在保存)", ErrVersionMismatch)
+	}
+	if detach != nil {
+		if err := detach(file); err != nil {
+			return FileData{}, err
+		}
+	}
+
+	file.Clicks += int(s.drainCounter(id))
+	removed := *file
+	delete(s.files, id)
+	s.counters.Delete(id)
+	s.markChanged()
+	return removed, nil
+}
+
+// 文件移除后清理行数缓存、点击趋势和编辑锁，并通知订阅方更新索引，调用时不能持有写锁；
+// path为文件移除前在磁盘上的位置
+func (s *FileStore) afterRemove(removed FileData, path string) {
+	forgetLineCount(path)
+	s.removeSeries(removed.ID)
+	s.dropEditLock(removed.ID)
+	s.notify(FileRemoved, removed)
+}
+
+// RenameFile 重命名文件；version不是AnyVersion时必须与文件当前版本一致
+func (s *FileStore) RenameFile(id string, newName string, version int) error {
 	log := logger.GetInstance()
 	
 	s.mu.Lock()
-	defer s.mu.Unlock()
 
 	file, exists := s.files[id]
 	if !exists {
+		s.mu.Unlock()
 		return fmt.Errorf("文件不存在")
 	}
+	if err := checkVersion(file, version); err != nil {
+		s.mu.Unlock()
+		return err
+	}
 
 	oldName := file.Name
 	file.Name = newName
+	file.Version++
 	s.dirty = true
 	s.cacheValid = false
+	renamed := *file
+	s.mu.Unlock()
 	
 	log.Info("✏️ 重命名文件: %s → %s (ID: %s)", oldName, newName, id)
 	s.triggerSave()
+	s.notify(FileRenamed, renamed)
 	return nil
 }
 
@@ -328,36 +604,35 @@ func (s *FileStore) CreateFile(name string, content string) (*FileData, error) {
 		return nil, fmt.Errorf("获取文件信息失败: %w", err)
 	}
 
+	mimeType, enc := detectFileType(fullPath)
+	now := time.Now()
 	fileData := &FileData{
-		ID:       id,
-		Name:     name,
-		Clicks:   0,
-		Size:     info.Size(),
-		UploadAt: time.Now(),
-		Path:     fullPath,
-	}
-
-	s.mu.Lock()
-	s.files[id] = fileData
-	s.dirty = true
-	s.cacheValid = false // 缓存失效
-	s.mu.Unlock()
-
+		ID:         id,
+		Name:       name,
+		Clicks:     0,
+		Size:       info.Size(),
+		UploadAt:   now,
+		Path:       fullPath,
+		MimeType:   mimeType,
+		Encoding:   enc,
+		ModifiedAt: now,
+		Hash:       hashBytes([]byte(content)),
+	}
+	if err := s.validateStored(fileData, false); err != nil {
+		file.Close()
+		os.Remove(fullPath)
+		return nil, err
+	}
+
+	s.addFile(fileData)
 	log.Info("✅ 文件创建成功: %s (ID: %s, 大小: %d bytes)", name, id, info.Size())
-	
-	s.triggerSave()
-	
-	// 异步通知更新
-	select {
-	case s.updateChan <- struct{}{}:
-	default:
-	}
-	
 	return fileData, nil
 }
 
-// 更新文件内容，保留原有文件信息
-func (s *FileStore) UpdateFileContent(id string, content string) error {
+// 更新文件内容，保留原有文件信息；encoding为空时按文件原来的编码写回，
+// version不是AnyVersion时必须与文件当前版本一致，
+// 文件被其他人锁定编辑时只有携带锁凭证lockToken才能写入
+func (s *FileStore) UpdateFileContent(id string, content string, encoding string, version int, lockToken string) error {
 	log := logger.GetInstance()
 	
 	s.mu.Lock()
@@ -366,56 +641,102 @@ func (s *FileStore) UpdateFileContent(id string, content string) error {
 		s.mu.Unlock()
 		return fmt.Errorf("文件不存在")
 	}
+	if err := checkVersion(file, version); err != nil {
+		s.mu.Unlock()
+		return err
+	}
 	
 	// 保存原有的文件信息
 	oldClicks := file.Clicks
 	oldName := file.Name
+	oldEncoding, path := file.Encoding, file.Path
+	if encoding == "" {
+		encoding = oldEncoding
+	}
 	s.mu.Unlock()
 
-	log.Info("📝 开始更新文件内容: %s (ID: %s, 当前点击: %d)", oldName, id, oldClicks)
-	
-	// 删除旧文件
-	if err := os.Remove(file.Path); err != nil && !os.IsNotExist(err) {
-		log.Error("❌ 删除旧文件失败: %v", err)
-		return fmt.Errorf("删除旧文件失败: %w", err)
+	if encoding == "" {
+		encoding = EncodingUTF8
 	}
-	
-	// 创建新文件并写入内容
-	newFile, err := os.Create(file.Path)
+	if !ValidEncoding(encoding) {
+		return fmt.Errorf("不支持的编码: %s", encoding)
+	}
+	// 先完成编码转换，失败时不改动原文件；按原编码写回时保持原文件是否带BOM
+	bom := true
+	if encoding == oldEncoding {
+		bom = hasUTF16BOM(path)
+	}
+	data, err := encodeText(content, encoding, bom)
 	if err != nil {
-		log.Error("❌ 创建新文件失败: %v", err)
-		return fmt.Errorf("创建新文件失败: %w", err)
+		return err
 	}
-	defer newFile.Close()
 
-	if content != "" {
-		_, err = newFile.WriteString(content)
-		if err != nil {
-			os.Remove(file.Path) // 清理失败文件
-			log.Error("❌ 写入文件内容失败: %v", err)
-			return fmt.Errorf("写入文件内容失败: %w", err)
-		}
+	// 写入期间占用该文件，基于同一版本的另一个写请求会被拒绝，不会交替写入；
+	// 版本号在写入成功后才增加，写入失败时客户端持有的版本仍然有效
+	s.mu.Lock()
+	if err := checkVersion(file, version); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	if err := s.locks.checkWriter(id, lockToken); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	if s.contentWriters[id] {
+		s.mu.Unlock()
+		return fmt.Errorf("%w (文件正在保存)", ErrVersionMismatch)
 	}
+	s.contentWriters[id] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.contentWriters, id)
+		s.mu.Unlock()
+	}()
 
-	// 获取新文件大小
-	info, err := newFile.Stat()
-	if err != nil {
-		log.Error("❌ 获取文件信息失败: %v", err)
-		return fmt.Errorf("获取文件信息失败: %w", err)
+	log.Info("📝 开始更新文件内容: %s (ID: %s, 当前点击: %d, 编码: %s)", oldName, id, oldClicks, encoding)
+
+	// 先写到临时文件再替换，失败时保留原内容
+	tempPath := file.Path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		os.Remove(tempPath)
+		log.Error("❌ 写入文件内容失败: %v", err)
+		return fmt.Errorf("写入文件内容失败: %w", err)
 	}
+	if err := os.Rename(tempPath, file.Path); err != nil {
+		os.Remove(tempPath)
+		log.Error("❌ 替换文件失败: %v", err)
+		return fmt.Errorf("替换文件失败: %w", err)
+	}
+	forgetLineCount(file.Path)
+
+	// 纯ASCII内容无法识别出原编码，以写入时使用的编码为准
+	mimeType := mimeWithEncoding(detectMIME(file.Path), encoding)
 
 	// 更新文件信息，保留原有数据并更新时间戳
 	s.mu.Lock()
-	file.Size = info.Size()
-	file.UploadAt = time.Now() // 更新时间戳为当前时间
+	if current, exists := s.files[id]; !exists || current != file {
+		s.mu.Unlock()
+		log.Warn("⚠️ 文件在保存期间已被移除: %s (ID: %s)", oldName, id)
+		return fmt.Errorf("文件不存在: %s", id)
+	}
+	file.Size = int64(len(data))
+	file.MimeType = mimeType
+	file.Encoding = encoding
+	file.ModifiedAt = time.Now() // 更新修改时间，上传时间保持不变
+	file.Hash = hashBytes(data)
+	file.Revision++
+	file.Version++
 	// 保留原有的Clicks和Name
 	s.dirty = true
 	s.cacheValid = false
+	updated := *file
 	s.mu.Unlock()
 
 	log.Info("✅ 文件内容更新成功: %s (ID: %s, 点击数: %d)", oldName, id, oldClicks)
 	
 	s.triggerSave()
+	s.notify(FileContentChanged, updated)
 	
 	// 异步通知更新
 	select {
@@ -426,40 +747,6 @@ func (s *FileStore) UpdateFileContent(id string, content string) error {
 	return nil
 }
 
-func (s *FileStore) GetFileContent(id string) (string, error) {
-	log := logger.GetInstance()
-	
-	s.mu.RLock()
-	file, exists := s.files[id]
-	s.mu.RUnlock()
-	
-	if !exists {
-		return "", fmt.Errorf("文件不存在")
-	}
-
-	// 检查文件是否存在
-	if _, err := os.Stat(file.Path); os.IsNotExist(err) {
-		log.Error("文件不存在: %s", file.Path)
-		return "", fmt.Errorf("文件不存在")
-	}
-
-	// 读取文件内容
-	content, err := os.ReadFile(file.Path)
-	if err != nil {
-		log.Error("读取文件失败: %v", err)
-		return "", fmt.Errorf("读取文件失败: %w", err)
-	}
-
-	// 限制显示大小（最多100KB）
-	maxSize := 100 * 1024
-	if len(content) > maxSize {
-		content = content[:maxSize]
-		return string(content) + "\n... (内容过长，已截断)", nil
-	}
-
-	return string(content), nil
-}
-
 func (s *FileStore) GetRanking() []FileData {
 	if !s.cacheValid {
 		s.buildRankedCache()
@@ -479,7 +766,7 @@ func (s *FileStore) GetFile(id string) (*FileData, bool) {
 		return nil, false
 	}
 
-	result := *file
+	result := s.withPending(*file)
 	return &result, true
 }
 
@@ -489,24 +776,21 @@ func (s *FileStore) GetAllFiles() []FileData {
 
 	files := make([]FileData, 0, len(s.files))
 	for _, file := range s.files {
-		files = append(files, *file)
+		files = append(files, s.withPending(*file))
 	}
 	return files
 }
 
 func (s *FileStore) Close() error {
-	close(s.saveChan)
-	close(s.updateChan)
+	close(s.done)
+	<-s.pipelineExited
+	<-s.events.exited
+	// 等自动保存退出，避免它用较早的快照覆盖最后一次保存；
+	// saveChan和updateChan不关闭，关闭过程中仍在提交的点击等写入方可以安全通知
+	<-s.autoSaveExited
 	return s.save()
 }
 
-// 批量操作类型
-type batchOperation struct {
-	type_   string
-	id      string
-	clicks  int
-}
-
 // 缓存失效检查
 func (s *FileStore) CacheInvalid() bool {
 	s.mu.RLock()
@@ -0,0 +1,389 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"file-ranking/internal/logger"
+)
+
+// RootFolderID 根目录，文件和文件夹的FolderID/ParentID为空时位于根目录
+const RootFolderID = ""
+
+// Folder 文件夹
+type Folder struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	ParentID  string    `json:"parent_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FolderSummary 文件夹及其汇总，Total*包含所有子文件夹
+type FolderSummary struct {
+	Folder
+	FileCount   int `json:"file_count"`
+	TotalFiles  int `json:"total_files"`
+	Clicks      int `json:"clicks"`
+	TotalClicks int `json:"total_clicks"`
+}
+
+// FolderListing 文件夹内容
+type FolderListing struct {
+	Folder     FolderSummary   `json:"folder"`
+	Path       []Folder        `json:"path"`
+	Subfolders []FolderSummary `json:"subfolders"`
+	Files      []FileData      `json:"files"`
+}
+
+func generateFolderID() string {
+	return fmt.Sprintf("fld_%d", time.Now().UnixNano())
+}
+
+func validateFolderName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("文件夹名称不能为空")
+	}
+	if strings.ContainsAny(name, "/\\") {
+		return "", fmt.Errorf("文件夹名称不能包含 / 或 \\")
+	}
+	return name, nil
+}
+
+// 同一父目录下是否已有同名文件夹，调用方需持有锁
+func (s *FileStore) siblingExists(parentID, name, exceptID string) bool {
+	for _, f := range s.folders {
+		if f.ParentID == parentID && f.Name == name && f.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
+
+// 检查文件夹是否存在，根目录总是存在，调用方需持有锁
+func (s *FileStore) folderExists(id string) bool {
+	if id == RootFolderID {
+		return true
+	}
+	_, ok := s.folders[id]
+	return ok
+}
+
+// id是否等于ancestor或位于其子树中，调用方需持有锁
+func (s *FileStore) inSubtree(id, ancestor string) bool {
+	if ancestor == RootFolderID {
+		return true
+	}
+	for id != RootFolderID {
+		if id == ancestor {
+			return true
+		}
+		f, ok := s.folders[id]
+		if !ok {
+			return false
+		}
+		id = f.ParentID
+	}
+	return false
+}
+
+// CreateFolder 在parentID下创建文件夹
+func (s *FileStore) CreateFolder(name, parentID string) (*Folder, error) {
+	name, err := validateFolderName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if !s.folderExists(parentID) {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("父文件夹不存在: %s", parentID)
+	}
+	if s.siblingExists(parentID, name, "") {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("文件夹已存在: %s", name)
+	}
+
+	folder := &Folder{
+		ID:        generateFolderID(),
+		Name:      name,
+		ParentID:  parentID,
+		CreatedAt: time.Now(),
+	}
+	s.folders[folder.ID] = folder
+	s.markChanged()
+	result := *folder
+	s.mu.Unlock()
+
+	logger.GetInstance().Info("📂 创建文件夹: %s (ID: %s, 父目录: %s)", name, folder.ID, parentID)
+	return &result, nil
+}
+
+// RenameFolder 重命名文件夹
+func (s *FileStore) RenameFolder(id, name string) (*Folder, error) {
+	name, err := validateFolderName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	folder, ok := s.folders[id]
+	if !ok {
+		return nil, fmt.Errorf("文件夹不存在: %s", id)
+	}
+	if s.siblingExists(folder.ParentID, name, id) {
+		return nil, fmt.Errorf("文件夹已存在: %s", name)
+	}
+
+	logger.GetInstance().Info("✏️ 重命名文件夹: %s → %s (ID: %s)", folder.Name, name, id)
+	folder.Name = name
+	s.markChanged()
+	result := *folder
+	return &result, nil
+}
+
+// MoveFolder 将文件夹移动到新的父目录，不能移动到自身或其子文件夹下
+func (s *FileStore) MoveFolder(id, parentID string) (*Folder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	folder, ok := s.folders[id]
+	if !ok {
+		return nil, fmt.Errorf("文件夹不存在: %s", id)
+	}
+	if !s.folderExists(parentID) {
+		return nil, fmt.Errorf("目标文件夹不存在: %s", parentID)
+	}
+	if s.inSubtree(parentID, id) {
+		return nil, fmt.Errorf("不能将文件夹移动到自身或其子文件夹下")
+	}
+	if s.siblingExists(parentID, folder.Name, id) {
+		return nil, fmt.Errorf("目标位置已存在同名文件夹: %s", folder.Name)
+	}
+
+	logger.GetInstance().Info("📂 移动文件夹: %s (ID: %s) %s → %s", folder.Name, id, folder.ParentID, parentID)
+	folder.ParentID = parentID
+	s.markChanged()
+	result := *folder
+	return &result, nil
+}
+
+// DeleteFolder 删除文件夹；非空文件夹需要recursive为true，此时连同子文件夹和文件一起删除
+func (s *FileStore) DeleteFolder(id string, recursive bool) error {
+	s.mu.Lock()
+	if _, ok := s.folders[id]; !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("文件夹不存在: %s", id)
+	}
+	var fileIDs []string
+	for fid, file := range s.files {
+		if s.inSubtree(file.FolderID, id) {
+			fileIDs = append(fileIDs, fid)
+		}
+	}
+	// 先收集再删除，保证子树判断正确
+	var folderIDs []string
+	for fid := range s.folders {
+		if s.inSubtree(fid, id) {
+			folderIDs = append(folderIDs, fid)
+		}
+	}
+	if !recursive && (len(fileIDs) > 0 || len(folderIDs) > 1) {
+		s.mu.Unlock()
+		return fmt.Errorf("文件夹不为空")
+	}
+
+	// 子树中有文件正在编辑或保存时整体拒绝，不留下删除了一半的目录树
+	for _, fid := range fileIDs {
+		name := s.files[fid].Name
+		if s.contentWriters[fid] {
+			s.mu.Unlock()
+			return fmt.Errorf("%w (文件正在保存: %s)", ErrVersionMismatch, name)
+		}
+		if lock := s.GetEditLock(fid); lock != nil {
+			s.mu.Unlock()
+			return fmt.Errorf("%w: %s (文件: %s)", ErrFileLocked, lock.Holder, name)
+		}
+	}
+
+	// 文件和文件夹在同一次加锁中删除，期间不会有文件移入
+	removed := make([]FileData, 0, len(fileIDs))
+	for _, fid := range fileIDs {
+		if file, err := s.removeLocked(fid, nil); err == nil {
+			removed = append(removed, file)
+		}
+	}
+	for _, fid := range folderIDs {
+		delete(s.folders, fid)
+	}
+	s.markChanged()
+	s.mu.Unlock()
+
+	log := logger.GetInstance()
+	for _, file := range removed {
+		if err := os.Remove(file.Path); err != nil && !os.IsNotExist(err) {
+			log.Warn("⚠️ 删除文件失败: %s - %v", file.Path, err)
+		}
+		s.afterRemove(file, file.Path)
+	}
+	log.Info("🗑️ 删除文件夹: %s (文件夹: %d, 文件: %d)", id, len(folderIDs), len(removed))
+	return nil
+}
+
+// MoveFile 将文件移动到指定文件夹
+func (s *FileStore) MoveFile(id, folderID string) (*FileData, error) {
+	s.mu.Lock()
+	file, exists := s.files[id]
+	if !exists {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("文件不存在: %s", id)
+	}
+	if !s.folderExists(folderID) {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("文件夹不存在: %s", folderID)
+	}
+
+	file.FolderID = folderID
+	s.markChanged()
+	result := s.withPending(*file)
+	s.mu.Unlock()
+
+	logger.GetInstance().Info("📂 移动文件: %s (ID: %s) → %s", result.Name, id, folderID)
+	s.notify(FileMetadataChanged, result)
+	return &result, nil
+}
+
+// 统计每个文件夹直接包含的文件数和点击数，并向上汇总，调用方需持有读锁
+func (s *FileStore) folderSummaries() map[string]*FolderSummary {
+	summaries := make(map[string]*FolderSummary, len(s.folders)+1)
+	summaries[RootFolderID] = &FolderSummary{Folder: Folder{ID: RootFolderID, Name: "/"}}
+	for id, f := range s.folders {
+		summaries[id] = &FolderSummary{Folder: *f}
+	}
+
+	for _, file := range s.files {
+		clicks := s.withPending(*file).Clicks
+		folderID := file.FolderID
+		if _, ok := summaries[folderID]; !ok {
+			folderID = RootFolderID
+		}
+		summaries[folderID].FileCount++
+		summaries[folderID].Clicks += clicks
+
+		// 向上累加到所有祖先
+		for id := folderID; ; {
+			sum := summaries[id]
+			sum.TotalFiles++
+			sum.TotalClicks += clicks
+			if id == RootFolderID {
+				break
+			}
+			id = sum.ParentID
+			if _, ok := summaries[id]; !ok {
+				id = RootFolderID
+			}
+		}
+	}
+	return summaries
+}
+
+// ListFolders 返回所有文件夹（含根目录）及汇总，按父目录和名称排序
+func (s *FileStore) ListFolders() []FolderSummary {
+	s.mu.RLock()
+	summaries := s.folderSummaries()
+	s.mu.RUnlock()
+
+	result := make([]FolderSummary, 0, len(summaries))
+	for _, sum := range summaries {
+		result = append(result, *sum)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ParentID != result[j].ParentID {
+			return result[i].ParentID < result[j].ParentID
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// ListFolder 列出文件夹的直接子文件夹和文件，文件按点击数降序
+func (s *FileStore) ListFolder(id string) (*FolderListing, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.folderExists(id) {
+		return nil, fmt.Errorf("文件夹不存在: %s", id)
+	}
+
+	summaries := s.folderSummaries()
+	listing := &FolderListing{
+		Folder:     *summaries[id],
+		Path:       make([]Folder, 0),
+		Subfolders: make([]FolderSummary, 0),
+		Files:      make([]FileData, 0),
+	}
+
+	for cur := id; cur != RootFolderID; {
+		f := s.folders[cur]
+		listing.Path = append([]Folder{*f}, listing.Path...)
+		cur = f.ParentID
+	}
+
+	for fid, f := range s.folders {
+		if f.ParentID == id {
+			listing.Subfolders = append(listing.Subfolders, *summaries[fid])
+		}
+	}
+	sort.Slice(listing.Subfolders, func(i, j int) bool {
+		return listing.Subfolders[i].Name < listing.Subfolders[j].Name
+	})
+
+	for _, file := range s.files {
+		if file.FolderID == id {
+			listing.Files = append(listing.Files, s.withPending(*file))
+		}
+	}
+	sort.Slice(listing.Files, func(i, j int) bool {
+		return listing.Files[i].Clicks > listing.Files[j].Clicks
+	})
+	return listing, nil
+}
+
+// GetFolderRanking 文件夹内的排行榜，recursive为true时包含子文件夹中的文件
+func (s *FileStore) GetFolderRanking(id string, recursive bool) ([]FileData, error) {
+	s.mu.RLock()
+	if !s.folderExists(id) {
+		s.mu.RUnlock()
+		return nil, fmt.Errorf("文件夹不存在: %s", id)
+	}
+	s.mu.RUnlock()
+
+	ranking := s.GetRanking()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]FileData, 0)
+	for _, file := range ranking {
+		if file.FolderID == id || (recursive && s.inSubtree(file.FolderID, id)) {
+			result = append(result, file)
+		}
+	}
+	return result, nil
+}
+
+// GetFolder 获取文件夹，根目录不是实际存储的文件夹
+func (s *FileStore) GetFolder(id string) (*Folder, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	folder, ok := s.folders[id]
+	if !ok {
+		return nil, false
+	}
+	result := *folder
+	return &result, true
+}
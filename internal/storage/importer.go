@@ -0,0 +1,536 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"file-ranking/internal/logger"
+	"file-ranking/internal/validation"
+)
+
+// 导入目录中的文件被删除后，对应文档的处理方式
+const (
+	ImportDeleteRemove = "remove" // 直接删除
+	ImportDeleteTrash  = "trash"  // 移入回收站
+)
+
+// DefaultImportInterval 导入目录的默认扫描间隔
+const DefaultImportInterval = 10 * time.Second
+
+// ErrImportDisabled 没有配置导入目录
+var ErrImportDisabled = errors.New("导入目录未启用")
+
+// ImportSource 导入文件在导入目录中的位置和上次同步时的状态
+type ImportSource struct {
+	// 相对导入目录的路径，以 / 分隔
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+func (src ImportSource) sameStat(other ImportSource) bool {
+	return src.Size == other.Size && src.ModTime.Equal(other.ModTime)
+}
+
+// ImportConfig 导入目录配置
+type ImportConfig struct {
+	Dir        string
+	Interval   time.Duration
+	DeleteMode string
+}
+
+// ImportScanResult 一次扫描的结果
+type ImportScanResult struct {
+	Time    time.Time `json:"time"`
+	Created int       `json:"created"`
+	Updated int       `json:"updated"`
+	Moved   int       `json:"moved"`
+	Removed int       `json:"removed"`
+	Failed  int       `json:"failed"`
+	// 正在写入、等待下次扫描确认的文件数
+	Pending int    `json:"pending"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ImportStatus 导入目录的同步状态
+type ImportStatus struct {
+	Dir        string            `json:"dir"`
+	Interval   string            `json:"interval"`
+	DeleteMode string            `json:"delete_mode"`
+	Tracked    int               `json:"tracked"`
+	LastScan   *ImportScanResult `json:"last_scan,omitempty"`
+}
+
+type importer struct {
+	store *FileStore
+	cfg   ImportConfig
+
+	mu sync.Mutex // 同一时间只进行一次扫描
+	// 上次扫描发现但还没导入的文件，两次扫描之间没有变化才认为已写完
+	pending map[string]ImportSource
+	// 上次扫描时已经不存在的文件，连续两次扫描都不存在才删除，以便识别移动
+	missing map[string]bool
+	// 未通过上传校验的文件，内容不变时不再重试
+	rejected map[string]ImportSource
+	last     *ImportScanResult
+}
+
+// StartImport 开始同步导入目录：先完成一次完整扫描，之后按间隔定期扫描
+func (s *FileStore) StartImport(cfg ImportConfig) error {
+	log := logger.GetInstance()
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultImportInterval
+	}
+	if cfg.DeleteMode == "" {
+		cfg.DeleteMode = ImportDeleteTrash
+	}
+	if cfg.DeleteMode != ImportDeleteRemove && cfg.DeleteMode != ImportDeleteTrash {
+		return fmt.Errorf("不支持的删除处理方式: %s", cfg.DeleteMode)
+	}
+	dir, err := filepath.Abs(cfg.Dir)
+	if err != nil {
+		return fmt.Errorf("导入目录无效: %w", err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("导入目录无效: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("导入目录不是目录: %s", dir)
+	}
+	cfg.Dir = dir
+
+	im := &importer{
+		store:    s,
+		cfg:      cfg,
+		pending:  make(map[string]ImportSource),
+		missing:  make(map[string]bool),
+		rejected: make(map[string]ImportSource),
+	}
+	s.mu.Lock()
+	s.importer = im
+	s.mu.Unlock()
+
+	log.Info("📥 开始同步导入目录: %s (间隔: %v, 删除处理: %s)", dir, cfg.Interval, cfg.DeleteMode)
+	im.scan(true)
+	go im.run()
+	return nil
+}
+
+// ScanImport 立即扫描一次导入目录
+func (s *FileStore) ScanImport() (*ImportScanResult, error) {
+	s.mu.RLock()
+	im := s.importer
+	s.mu.RUnlock()
+	if im == nil {
+		return nil, ErrImportDisabled
+	}
+	return im.scan(false), nil
+}
+
+// GetImportStatus 导入目录的配置和最近一次扫描结果
+func (s *FileStore) GetImportStatus() (*ImportStatus, error) {
+	s.mu.RLock()
+	im := s.importer
+	tracked := 0
+	for _, file := range s.files {
+		if file.Import != nil {
+			tracked++
+		}
+	}
+	s.mu.RUnlock()
+	if im == nil {
+		return nil, ErrImportDisabled
+	}
+
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	status := &ImportStatus{
+		Dir:        im.cfg.Dir,
+		Interval:   im.cfg.Interval.String(),
+		DeleteMode: im.cfg.DeleteMode,
+		Tracked:    tracked,
+	}
+	if im.last != nil {
+		last := *im.last
+		status.LastScan = &last
+	}
+	return status, nil
+}
+
+func (im *importer) run() {
+	ticker := time.NewTicker(im.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			im.scan(false)
+		case <-im.store.done:
+			return
+		}
+	}
+}
+
+// 扫描导入目录并与已导入的文档对比；首次扫描时较早修改的文件直接导入
+func (im *importer) scan(initial bool) *ImportScanResult {
+	log := logger.GetInstance()
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	result := &ImportScanResult{Time: time.Now()}
+	defer func() { im.last = result }()
+
+	seen, err := im.walk()
+	if err != nil {
+		// 目录不可读（如网络共享断开）时不做任何删除
+		log.Error("❌ 扫描导入目录失败: %v", err)
+		result.Error = err.Error()
+		return result
+	}
+
+	tracked := im.store.importedFiles()
+	missing := make(map[string]FileData)
+	for rel, file := range tracked {
+		if _, ok := seen[rel]; !ok {
+			missing[rel] = file
+		}
+	}
+
+	for rel, src := range seen {
+		file, exists := tracked[rel]
+		if exists && file.Import.sameStat(src) {
+			delete(im.pending, rel)
+			continue
+		}
+		if rejected, ok := im.rejected[rel]; ok && rejected.sameStat(src) {
+			continue
+		}
+		settled := initial && result.Time.Sub(src.ModTime) > im.cfg.Interval
+		if pending, ok := im.pending[rel]; !settled && (!ok || !pending.sameStat(src)) {
+			im.pending[rel] = src
+			continue
+		}
+		delete(im.pending, rel)
+
+		var err error
+		switch {
+		case exists:
+			if err = im.store.updateImported(file.ID, im.cfg.Dir, src); err == nil {
+				result.Updated++
+			}
+		default:
+			if moved, ok := im.findMoved(missing, src); ok {
+				err = im.store.moveImported(moved.ID, src)
+				if err == nil || errors.Is(err, ErrFileLocked) {
+					delete(missing, moved.Import.Path)
+				}
+				if err == nil {
+					result.Moved++
+				}
+				break
+			}
+			if err = im.store.createImported(im.cfg.Dir, src); err == nil {
+				result.Created++
+			}
+		}
+		if errors.Is(err, ErrFileLocked) {
+			// 正在编辑的文件不覆盖，下次扫描时重试
+			log.Info("🔒 导入文件正在编辑，下次扫描再同步: %s", rel)
+			im.pending[rel] = src
+			continue
+		}
+		if err != nil {
+			log.Error("❌ 同步导入文件失败: %s - %v", rel, err)
+			// 只有未通过校验的文件在内容不变时不再重试，读写失败等其他错误下次扫描重试
+			var verr *validation.Error
+			if errors.As(err, &verr) {
+				im.rejected[rel] = src
+			} else {
+				delete(im.rejected, rel)
+			}
+			result.Failed++
+			continue
+		}
+		delete(im.rejected, rel)
+	}
+
+	nextMissing := make(map[string]bool)
+	for rel, file := range missing {
+		// 正在编辑的文件等编辑结束后再移除
+		if !im.missing[rel] || im.store.GetEditLock(file.ID) != nil {
+			nextMissing[rel] = true
+			continue
+		}
+		var err error
+		if im.cfg.DeleteMode == ImportDeleteRemove {
+			err = im.store.RemoveFile(file.ID, AnyVersion)
+		} else {
+			err = im.store.TrashFile(file.ID)
+		}
+		if err != nil {
+			log.Error("❌ 移除已删除的导入文件失败: %s - %v", rel, err)
+			result.Failed++
+			continue
+		}
+		result.Removed++
+	}
+	im.missing = nextMissing
+
+	for rel := range im.pending {
+		if _, ok := seen[rel]; !ok {
+			delete(im.pending, rel)
+		}
+	}
+	for rel := range im.rejected {
+		if _, ok := seen[rel]; !ok {
+			delete(im.rejected, rel)
+		}
+	}
+	result.Pending = len(im.pending)
+
+	if result.Created+result.Updated+result.Moved+result.Removed+result.Failed > 0 {
+		log.Info("📥 导入目录同步完成: 新增 %d, 更新 %d, 移动 %d, 移除 %d, 失败 %d",
+			result.Created, result.Updated, result.Moved, result.Removed, result.Failed)
+	}
+	return result
+}
+
+// 列出导入目录中的所有普通文件，跳过隐藏文件和常见的临时文件
+func (im *importer) walk() (map[string]ImportSource, error) {
+	root := im.cfg.Dir
+	seen := make(map[string]ImportSource)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			logger.GetInstance().Warn("⚠️ 读取导入目录失败: %s - %v", path, err)
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if path == root {
+			return nil
+		}
+		if ignoredImportName(d.Name()) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		seen[rel] = ImportSource{Path: rel, Size: info.Size(), ModTime: info.ModTime()}
+		return nil
+	})
+	return seen, err
+}
+
+func ignoredImportName(name string) bool {
+	lower := strings.ToLower(name)
+	if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "~$") || lower == "thumbs.db" || lower == "desktop.ini" {
+		return true
+	}
+	for _, ext := range []string{".tmp", ".part", ".crdownload", ".swp"} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// 在本次扫描消失的文件中查找大小和内容都相同的，认为是移动或重命名
+func (im *importer) findMoved(missing map[string]FileData, src ImportSource) (FileData, bool) {
+	var hash string
+	for _, file := range missing {
+		if file.Size != src.Size || file.Hash == "" {
+			continue
+		}
+		if hash == "" {
+			var err error
+			if hash, err = hashFile(filepath.Join(im.cfg.Dir, filepath.FromSlash(src.Path))); err != nil {
+				return FileData{}, false
+			}
+		}
+		if file.Hash == hash {
+			return file, true
+		}
+	}
+	return FileData{}, false
+}
+
+// 所有从导入目录同步的文档，按相对路径索引
+func (s *FileStore) importedFiles() map[string]FileData {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	files := make(map[string]FileData)
+	for _, file := range s.files {
+		if file.Import != nil {
+			files[file.Import.Path] = *file
+		}
+	}
+	return files
+}
+
+// 导入新文件，文件名保留导入目录中的相对路径
+func (s *FileStore) createImported(root string, src ImportSource) error {
+	f, err := os.Open(filepath.Join(root, filepath.FromSlash(src.Path)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	outcome, err := s.UploadFileWithPolicy(src.Path, f, DuplicateKeep)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if file, ok := s.files[outcome.File.ID]; ok {
+		source := src
+		file.Import = &source
+		s.dirty = true
+	}
+	s.mu.Unlock()
+	s.triggerSave()
+	return nil
+}
+
+// 导入目录中的文件被修改后同步内容，点击数和元数据保留，内容变化时版本号加1；
+// 文件正在编辑时返回 ErrFileLocked
+func (s *FileStore) updateImported(id, root string, src ImportSource) error {
+	log := logger.GetInstance()
+
+	if err := s.locks.checkWriter(id, ""); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	file, exists := s.files[id]
+	var current FileData
+	if exists {
+		current = *file
+	}
+	s.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("文件不存在: %s", id)
+	}
+
+	in, err := os.Open(filepath.Join(root, filepath.FromSlash(src.Path)))
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	// 先写到临时文件，校验通过后再替换，失败时保留原内容
+	tempPath := current.Path + ".import"
+	out, err := os.Create(tempPath)
+	if err != nil {
+		return fmt.Errorf("创建文件失败: %w", err)
+	}
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(out, hasher), in)
+	out.Close()
+	if err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("写入文件失败: %w", err)
+	}
+	hash := hex.EncodeToString(hasher.Sum(nil))
+
+	if hash == current.Hash {
+		// 只是修改时间变化，内容没变
+		os.Remove(tempPath)
+		s.mu.Lock()
+		if file, ok := s.files[id]; ok {
+			source := src
+			file.Import = &source
+			s.dirty = true
+		}
+		s.mu.Unlock()
+		s.triggerSave()
+		return nil
+	}
+
+	mimeType, enc := detectFileType(tempPath)
+	candidate := current
+	candidate.Path, candidate.Size, candidate.MimeType, candidate.Encoding = tempPath, size, mimeType, enc
+	if err := s.validateStored(&candidate, false); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	if err := os.Rename(tempPath, current.Path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("保存文件失败: %w", err)
+	}
+	forgetLineCount(current.Path)
+
+	s.mu.Lock()
+	file, exists = s.files[id]
+	if !exists {
+		s.mu.Unlock()
+		return fmt.Errorf("文件不存在: %s", id)
+	}
+	source := src
+	file.Size = size
+	file.MimeType = mimeType
+	file.Encoding = enc
+	file.Hash = hash
+	file.ModifiedAt = time.Now()
+	file.Revision++
+	file.Version++
+	file.Import = &source
+	s.markChanged()
+	updated := s.withPending(*file)
+	s.mu.Unlock()
+
+	log.Info("📥 导入文件已更新: %s (ID: %s, 版本: %d)", updated.Name, id, updated.Revision)
+	s.notify(FileContentChanged, updated)
+	return nil
+}
+
+// 导入目录中的文件被移动或重命名，保留文档ID和点击数，文件名随之更新；
+// 文件正在编辑时返回 ErrFileLocked
+func (s *FileStore) moveImported(id string, src ImportSource) error {
+	s.mu.Lock()
+	file, exists := s.files[id]
+	if !exists {
+		s.mu.Unlock()
+		return fmt.Errorf("文件不存在: %s", id)
+	}
+	if err := s.locks.checkWriter(id, ""); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	oldName := file.Name
+	source := src
+	file.Name = src.Path
+	file.Version++
+	file.Import = &source
+	s.markChanged()
+	moved := s.withPending(*file)
+	s.mu.Unlock()
+
+	logger.GetInstance().Info("📥 导入文件已移动: %s → %s (ID: %s)", oldName, moved.Name, id)
+	s.notify(FileRenamed, moved)
+	return nil
+}
@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"file-ranking/internal/logger"
+)
+
+// 标签和元数据的限制
+const (
+	maxTags           = 20
+	maxTagLength      = 32
+	maxDescLength     = 2000
+	maxMetadataKeys   = 50
+	maxMetadataKeyLen = 64
+	maxMetadataValLen = 1024
+)
+
+// MetadataUpdate 元数据更新，为nil的字段保持不变；
+// Metadata中值为nil的键会被删除，其余键新增或覆盖
+type MetadataUpdate struct {
+	Tags        *[]string          `json:"tags"`
+	Description *string            `json:"description"`
+	Metadata    map[string]*string `json:"metadata"`
+}
+
+// TagCount 标签及使用该标签的文件数
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// 去除空白、空标签和重复标签（不区分大小写），按字母序排列
+func normalizeTags(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool, len(tags))
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, tag)
+	}
+	sort.Strings(result)
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// HasTags 文件是否包含全部指定标签（不区分大小写）
+func (f *FileData) HasTags(tags []string) bool {
+	for _, want := range tags {
+		found := false
+		for _, tag := range f.Tags {
+			if strings.EqualFold(tag, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func validateMetadataUpdate(u MetadataUpdate) error {
+	if u.Tags != nil {
+		if len(*u.Tags) > maxTags {
+			return fmt.Errorf("标签数量不能超过 %d 个", maxTags)
+		}
+		for _, tag := range *u.Tags {
+			if utf8.RuneCountInString(strings.TrimSpace(tag)) > maxTagLength {
+				return fmt.Errorf("标签长度不能超过 %d 个字符: %s", maxTagLength, tag)
+			}
+		}
+	}
+	if u.Description != nil && utf8.RuneCountInString(*u.Description) > maxDescLength {
+		return fmt.Errorf("描述长度不能超过 %d 个字符", maxDescLength)
+	}
+	for key, value := range u.Metadata {
+		if strings.TrimSpace(key) == "" || len(key) > maxMetadataKeyLen {
+			return fmt.Errorf("元数据键无效: %q", key)
+		}
+		if value != nil && len(*value) > maxMetadataValLen {
+			return fmt.Errorf("元数据值长度不能超过 %d 字节: %s", maxMetadataValLen, key)
+		}
+	}
+	return nil
+}
+
+// UpdateMetadata 更新文件的标签、描述和自定义元数据
+func (s *FileStore) UpdateMetadata(id string, u MetadataUpdate) (*FileData, error) {
+	if err := validateMetadataUpdate(u); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	file, exists := s.files[id]
+	if !exists {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("文件不存在: %s", id)
+	}
+
+	if u.Tags != nil {
+		file.Tags = normalizeTags(*u.Tags)
+	}
+	if u.Description != nil {
+		file.Description = strings.TrimSpace(*u.Description)
+	}
+	if u.Metadata != nil {
+		// 复制后修改，已返回给调用方的快照不受影响
+		metadata := make(map[string]string, len(file.Metadata)+len(u.Metadata))
+		for k, v := range file.Metadata {
+			metadata[k] = v
+		}
+		for k, v := range u.Metadata {
+			if v == nil {
+				delete(metadata, k)
+			} else {
+				metadata[k] = *v
+			}
+		}
+		if len(metadata) > maxMetadataKeys {
+			s.mu.Unlock()
+			return nil, fmt.Errorf("元数据键数量不能超过 %d 个", maxMetadataKeys)
+		}
+		if len(metadata) == 0 {
+			metadata = nil
+		}
+		file.Metadata = metadata
+	}
+
+	// 版本号只跟踪名称和内容，修改元数据不会让正在编辑内容的客户端版本失效
+	result := s.withPending(*file)
+	s.markChanged()
+	s.mu.Unlock()
+
+	logger.GetInstance().Info("🏷️ 更新文件元数据: %s (ID: %s, 标签: %v)", result.Name, id, result.Tags)
+	s.notify(FileMetadataChanged, result)
+	return &result, nil
+}
+
+// GetTags 统计所有标签的使用次数，按次数降序
+func (s *FileStore) GetTags() []TagCount {
+	s.mu.RLock()
+	counts := make(map[string]int)
+	display := make(map[string]string)
+	for _, file := range s.files {
+		for _, tag := range file.Tags {
+			key := strings.ToLower(tag)
+			counts[key]++
+			if _, ok := display[key]; !ok {
+				display[key] = tag
+			}
+		}
+	}
+	s.mu.RUnlock()
+
+	result := make([]TagCount, 0, len(counts))
+	for key, n := range counts {
+		result = append(result, TagCount{Tag: display[key], Count: n})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Tag < result[j].Tag
+	})
+	return result
+}
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"errors"
+	"mime"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// 无法识别类型时使用的默认值
+const defaultMIME = "application/octet-stream"
+
+// ErrBinaryFile 二进制文件不能作为文本读取
+var ErrBinaryFile = errors.New("二进制文件不支持以文本方式查看，请下载后打开")
+
+// 根据文件头部的特征字节识别类型
+func detectMIME(path string) string {
+	m, err := mimetype.DetectFile(path)
+	if err != nil {
+		return defaultMIME
+	}
+	return m.String()
+}
+
+// IsTextMIME 判断类型是否属于文本，HTML、JSON、CSV等都以text/plain为祖先
+func IsTextMIME(mimeType string) bool {
+	// Lookup不识别charset等参数
+	if mediaType, _, err := mime.ParseMediaType(mimeType); err == nil {
+		mimeType = mediaType
+	}
+	for m := mimetype.Lookup(mimeType); m != nil; m = m.Parent() {
+		if m.Is("text/plain") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// 当前数据文件格式版本
+//
+//	v1: 文件列表数组
+//	v2: {"version": 2, "files": [...]}，文件增加标签、描述和元数据
+//	v3: 增加 "folders"，文件增加所在文件夹
+const snapshotVersion = 3
+
+type snapshot struct {
+	Version int        `json:"version"`
+	Files   []FileData `json:"files"`
+	Folders []Folder   `json:"folders,omitempty"`
+	// 开始记录点击趋势的时间
+	SeriesSince *time.Time `json:"series_since,omitempty"`
+}
+
+// 解析数据文件，兼容v1的数组格式
+func decodeSnapshot(data []byte) (*snapshot, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var files []FileData
+		if err := json.Unmarshal(trimmed, &files); err != nil {
+			return nil, fmt.Errorf("解析JSON失败: %w", err)
+		}
+		return &snapshot{Version: 1, Files: files}, nil
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(trimmed, &snap); err != nil {
+		return nil, fmt.Errorf("解析JSON失败: %w", err)
+	}
+	if snap.Version > snapshotVersion {
+		return nil, fmt.Errorf("不支持的数据格式版本: %d", snap.Version)
+	}
+	return &snap, nil
+}
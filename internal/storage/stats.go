@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"math"
+	"math/bits"
+)
+
+// HistogramBucket 点击数直方图的一个区间，[Min, Max]闭区间
+type HistogramBucket struct {
+	Min   int `json:"min"`
+	Max   int `json:"max"`
+	Count int `json:"count"`
+}
+
+// LeaderboardStats 排行榜汇总与点击分布
+type LeaderboardStats struct {
+	TotalFiles     int                `json:"total_files"`
+	TotalClicks    int                `json:"total_clicks"`
+	TotalBytes     int64              `json:"total_bytes"`
+	ZeroClickFiles int                `json:"zero_click_files"`
+	MinClicks      int                `json:"min_clicks"`
+	MaxClicks      int                `json:"max_clicks"`
+	MeanClicks     float64            `json:"mean_clicks"`
+	MedianClicks   float64            `json:"median_clicks"`
+	Percentiles    map[string]float64 `json:"percentiles"`
+	Top10Share     float64            `json:"top10_share"`
+	Gini           float64            `json:"gini"`
+	Histogram      []HistogramBucket  `json:"histogram"`
+}
+
+var statsPercentiles = []struct {
+	name string
+	p    float64
+}{
+	{"p25", 0.25}, {"p50", 0.50}, {"p75", 0.75}, {"p90", 0.90}, {"p95", 0.95}, {"p99", 0.99},
+}
+
+// GetStats 基于排行榜缓存计算汇总和点击分布
+func (s *FileStore) GetStats() LeaderboardStats {
+	s.foldClicks()
+	ranking := s.GetRanking()
+
+	stats := LeaderboardStats{
+		TotalFiles:  len(ranking),
+		Percentiles: make(map[string]float64, len(statsPercentiles)),
+		Histogram:   make([]HistogramBucket, 0),
+	}
+	n := len(ranking)
+	if n == 0 {
+		return stats
+	}
+
+	// 排行榜按点击数降序，转换为升序便于计算分位数
+	asc := make([]int, n)
+	for i, file := range ranking {
+		asc[n-1-i] = file.Clicks
+		stats.TotalClicks += file.Clicks
+		stats.TotalBytes += file.Size
+		if file.Clicks == 0 {
+			stats.ZeroClickFiles++
+		}
+	}
+
+	stats.MinClicks = asc[0]
+	stats.MaxClicks = asc[n-1]
+	stats.MeanClicks = float64(stats.TotalClicks) / float64(n)
+	stats.MedianClicks = percentile(asc, 0.5)
+	for _, sp := range statsPercentiles {
+		stats.Percentiles[sp.name] = percentile(asc, sp.p)
+	}
+
+	if stats.TotalClicks > 0 {
+		top := 0
+		for i := 0; i < n && i < 10; i++ {
+			top += ranking[i].Clicks
+		}
+		stats.Top10Share = float64(top) / float64(stats.TotalClicks)
+
+		var weighted float64
+		for i, clicks := range asc {
+			weighted += float64(i+1) * float64(clicks)
+		}
+		stats.Gini = 2*weighted/(float64(n)*float64(stats.TotalClicks)) - float64(n+1)/float64(n)
+	}
+
+	stats.Histogram = histogram(asc)
+	return stats
+}
+
+// 线性插值分位数，values需升序
+func percentile(values []int, p float64) float64 {
+	if len(values) == 1 {
+		return float64(values[0])
+	}
+	pos := p * float64(len(values)-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	frac := pos - float64(lo)
+	return float64(values[lo]) + frac*float64(values[hi]-values[lo])
+}
+
+// 按2的幂划分区间：0, 1, 2-3, 4-7, ...，适合长尾分布；values需升序
+func histogram(values []int) []HistogramBucket {
+	buckets := []HistogramBucket{{Min: 0, Max: 0}}
+	for lo := 1; lo <= values[len(values)-1]; lo *= 2 {
+		buckets = append(buckets, HistogramBucket{Min: lo, Max: lo*2 - 1})
+	}
+
+	for _, v := range values {
+		idx := bits.Len(uint(v))
+		if idx >= len(buckets) {
+			idx = len(buckets) - 1
+		}
+		buckets[idx].Count++
+	}
+	return buckets
+}
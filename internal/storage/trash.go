@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"file-ranking/internal/logger"
+)
+
+func (s *FileStore) trashDir() string {
+	return filepath.Join(s.uploadDir, ".trash")
+}
+
+// TrashFile 从排行榜移除文件，但把文件移入回收站目录而不是删除，
+// 文件信息（含点击数）保存在同名的 .json 中，需要时可以手动恢复
+func (s *FileStore) TrashFile(id string) error {
+	log := logger.GetInstance()
+	if err := os.MkdirAll(s.trashDir(), 0755); err != nil {
+		return fmt.Errorf("创建回收站目录失败: %w", err)
+	}
+
+	s.mu.Lock()
+	var target string
+	trashed, err := s.removeLocked(id, func(file *FileData) error {
+		target = filepath.Join(s.trashDir(), filepath.Base(file.Path))
+		if err := os.Rename(file.Path, target); err != nil && !os.IsNotExist(err) {
+			log.Error("❌ 移入回收站失败: %v", err)
+			return fmt.Errorf("移入回收站失败: %w", err)
+		}
+		return nil
+	})
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	oldPath := trashed.Path
+	trashed.Path = target
+
+	if data, err := json.MarshalIndent(trashed, "", "  "); err == nil {
+		if err := os.WriteFile(target+".json", data, 0644); err != nil {
+			log.Warn("⚠️ 保存回收站记录失败: %v", err)
+		}
+	}
+
+	log.Info("🗑️ 文件已移入回收站: %s (ID: %s)", trashed.Name, id)
+	s.afterRemove(trashed, oldPath)
+	return nil
+}
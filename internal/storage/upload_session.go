@@ -0,0 +1,387 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"file-ranking/internal/logger"
+)
+
+const (
+	// MaxResumableSize 分片上传的文件大小上限
+	MaxResumableSize = 4 << 30
+	// DefaultUploadSessionTTL 上传会话无活动多久后被清理
+	DefaultUploadSessionTTL = 24 * time.Hour
+
+	uploadGCInterval = 10 * time.Minute
+)
+
+// UploadSession 分片上传会话，Received为服务端已持久化的字节数
+type UploadSession struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	Received  int64     `json:"received"`
+	FolderID  string    `json:"folder_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// OffsetMismatchError 分片偏移与服务端已接收的字节数不一致
+type OffsetMismatchError struct {
+	Expected int64
+}
+
+func (e *OffsetMismatchError) Error() string {
+	return fmt.Sprintf("分片偏移不匹配，服务端已接收 %d 字节", e.Expected)
+}
+
+// ErrUploadSessionNotFound 上传会话不存在或已过期
+var ErrUploadSessionNotFound = errors.New("上传会话不存在或已过期")
+
+type uploadSession struct {
+	mu   sync.Mutex
+	info UploadSession
+}
+
+// 未完成的上传会话，元数据和已接收的数据都保存在磁盘上，重启后可以继续
+type uploadSessions struct {
+	dir      string
+	mu       sync.Mutex
+	sessions map[string]*uploadSession
+	ttl      time.Duration
+}
+
+func newUploadSessions(dir string) (*uploadSessions, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建上传会话目录失败: %w", err)
+	}
+	us := &uploadSessions{
+		dir:      dir,
+		sessions: make(map[string]*uploadSession),
+		ttl:      DefaultUploadSessionTTL,
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取上传会话失败: %w", err)
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		var info UploadSession
+		if err := json.Unmarshal(data, &info); err != nil || info.ID == "" {
+			continue
+		}
+		// 以磁盘上实际写入的数据为准
+		stat, err := os.Stat(us.partPath(info.ID))
+		if err != nil {
+			continue
+		}
+		info.Received = stat.Size()
+		us.sessions[info.ID] = &uploadSession{info: info}
+	}
+	return us, nil
+}
+
+func (us *uploadSessions) partPath(id string) string {
+	return filepath.Join(us.dir, id+".part")
+}
+
+func (us *uploadSessions) metaPath(id string) string {
+	return filepath.Join(us.dir, id+".json")
+}
+
+func (us *uploadSessions) get(id string) (*uploadSession, bool) {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	sess, ok := us.sessions[id]
+	return sess, ok
+}
+
+func (us *uploadSessions) remove(id string) {
+	us.mu.Lock()
+	delete(us.sessions, id)
+	us.mu.Unlock()
+	os.Remove(us.partPath(id))
+	os.Remove(us.metaPath(id))
+}
+
+// 保存会话元数据，调用方需持有会话锁
+func (us *uploadSessions) persist(sess *uploadSession) error {
+	data, err := json.Marshal(sess.info)
+	if err != nil {
+		return err
+	}
+	tempPath := us.metaPath(sess.info.ID) + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, us.metaPath(sess.info.ID))
+}
+
+func (us *uploadSessions) snapshot(sess *uploadSession) *UploadSession {
+	info := sess.info
+	us.mu.Lock()
+	info.ExpiresAt = info.UpdatedAt.Add(us.ttl)
+	us.mu.Unlock()
+	return &info
+}
+
+// SetUploadSessionTTL 设置未完成上传会话的保留时间
+func (s *FileStore) SetUploadSessionTTL(ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("上传会话保留时间必须大于0")
+	}
+	s.uploads.mu.Lock()
+	s.uploads.ttl = ttl
+	s.uploads.mu.Unlock()
+	return nil
+}
+
+// CreateUploadSession 创建分片上传会话，size为文件总大小
+func (s *FileStore) CreateUploadSession(name string, size int64, folderID string) (*UploadSession, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("文件名不能为空")
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("文件大小必须大于0")
+	}
+	if size > MaxResumableSize {
+		return nil, fmt.Errorf("文件大小不能超过 %dGB", MaxResumableSize>>30)
+	}
+	if err := s.PrecheckUpload(name, size, true); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	folderOK := s.folderExists(folderID)
+	s.mu.RUnlock()
+	if !folderOK {
+		return nil, fmt.Errorf("文件夹不存在: %s", folderID)
+	}
+
+	now := time.Now()
+	sess := &uploadSession{info: UploadSession{
+		ID:        fmt.Sprintf("up_%d", now.UnixNano()),
+		Name:      name,
+		Size:      size,
+		FolderID:  folderID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}}
+
+	us := s.uploads
+	f, err := os.Create(us.partPath(sess.info.ID))
+	if err != nil {
+		return nil, fmt.Errorf("创建上传会话失败: %w", err)
+	}
+	f.Close()
+	if err := us.persist(sess); err != nil {
+		os.Remove(us.partPath(sess.info.ID))
+		return nil, fmt.Errorf("创建上传会话失败: %w", err)
+	}
+
+	us.mu.Lock()
+	us.sessions[sess.info.ID] = sess
+	us.mu.Unlock()
+
+	logger.GetInstance().Info("📤 创建上传会话: %s (ID: %s, 大小: %d bytes)", name, sess.info.ID, size)
+	return us.snapshot(sess), nil
+}
+
+// GetUploadSession 查询上传进度
+func (s *FileStore) GetUploadSession(id string) (*UploadSession, error) {
+	sess, ok := s.uploads.get(id)
+	if !ok {
+		return nil, ErrUploadSessionNotFound
+	}
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return s.uploads.snapshot(sess), nil
+}
+
+// WriteUploadChunk 从offset处追加一个分片，offset必须等于已接收的字节数；
+// 传输中断时已写入的部分仍会保留，客户端查询进度后从新的偏移继续
+func (s *FileStore) WriteUploadChunk(id string, offset int64, r io.Reader) (*UploadSession, error) {
+	us := s.uploads
+	sess, ok := us.get(id)
+	if !ok {
+		return nil, ErrUploadSessionNotFound
+	}
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	if offset != sess.info.Received {
+		return us.snapshot(sess), &OffsetMismatchError{Expected: sess.info.Received}
+	}
+
+	f, err := os.OpenFile(us.partPath(id), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("写入分片失败: %w", err)
+	}
+	defer f.Close()
+
+	remaining := sess.info.Size - sess.info.Received
+	n, copyErr := io.Copy(f, io.LimitReader(r, remaining))
+	if copyErr == nil && n == remaining {
+		// 超出声明大小的分片整体拒绝，撤销本次写入
+		var probe [1]byte
+		if m, _ := r.Read(probe[:]); m > 0 {
+			if err := f.Truncate(sess.info.Received); err != nil {
+				return nil, fmt.Errorf("写入分片失败: %w", err)
+			}
+			return us.snapshot(sess), fmt.Errorf("数据超出声明的文件大小 %d bytes", sess.info.Size)
+		}
+	}
+
+	sess.info.Received += n
+	sess.info.UpdatedAt = time.Now()
+	if err := us.persist(sess); err != nil {
+		return nil, fmt.Errorf("保存上传进度失败: %w", err)
+	}
+	if copyErr != nil {
+		return us.snapshot(sess), fmt.Errorf("写入分片失败: %w", copyErr)
+	}
+	return us.snapshot(sess), nil
+}
+
+// FinalizeUpload 所有数据接收完成后登记为文件，内容与已有文件相同时按policy处理
+func (s *FileStore) FinalizeUpload(id, policy string) (*UploadOutcome, error) {
+	log := logger.GetInstance()
+	us := s.uploads
+	sess, ok := us.get(id)
+	if !ok {
+		return nil, ErrUploadSessionNotFound
+	}
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	info := sess.info
+	if info.Received != info.Size {
+		return nil, fmt.Errorf("上传未完成: 已接收 %d / %d bytes", info.Received, info.Size)
+	}
+
+	// 先在会话目录中计算哈希，失败时会话和已接收的数据保持不变，客户端可以重试
+	f, err := os.Open(us.partPath(id))
+	if err != nil {
+		return nil, fmt.Errorf("读取上传数据失败: %w", err)
+	}
+	hasher := sha256.New()
+	_, err = io.Copy(hasher, f)
+	f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("计算文件哈希失败: %w", err)
+	}
+
+	fileID := generateID()
+	fullPath := filepath.Join(s.uploadDir, fileID+"_"+sanitizeFilename(info.Name))
+	if err := os.Rename(us.partPath(id), fullPath); err != nil {
+		return nil, fmt.Errorf("保存文件失败: %w", err)
+	}
+
+	// 上传期间文件夹可能已被删除
+	s.mu.RLock()
+	folderID := info.FolderID
+	if !s.folderExists(folderID) {
+		folderID = RootFolderID
+	}
+	s.mu.RUnlock()
+
+	mimeType, enc := detectFileType(fullPath)
+	now := time.Now()
+	fileData := &FileData{
+		ID:         fileID,
+		Name:       info.Name,
+		Size:       info.Size,
+		UploadAt:   now,
+		Path:       fullPath,
+		FolderID:   folderID,
+		MimeType:   mimeType,
+		Encoding:   enc,
+		ModifiedAt: now,
+		Hash:       hex.EncodeToString(hasher.Sum(nil)),
+	}
+	// 未通过校验的数据直接丢弃，会话随之结束
+	if err := s.validateStored(fileData, true); err != nil {
+		os.Remove(fullPath)
+		us.remove(id)
+		return nil, err
+	}
+	outcome := s.registerUpload(fileData, policy)
+	us.remove(id)
+
+	log.Info("✅ 分片上传完成: %s (ID: %s, 大小: %d bytes)", info.Name, outcome.File.ID, info.Size)
+	return outcome, nil
+}
+
+// AbortUpload 取消上传并删除已接收的数据
+func (s *FileStore) AbortUpload(id string) error {
+	sess, ok := s.uploads.get(id)
+	if !ok {
+		return ErrUploadSessionNotFound
+	}
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	s.uploads.remove(id)
+	logger.GetInstance().Info("🗑️ 取消上传会话: %s (ID: %s)", sess.info.Name, id)
+	return nil
+}
+
+// PruneUploadSessions 清理超过保留时间没有活动的上传会话，返回清理数量
+func (s *FileStore) PruneUploadSessions(now time.Time) int {
+	us := s.uploads
+	us.mu.Lock()
+	ttl := us.ttl
+	candidates := make([]*uploadSession, 0, len(us.sessions))
+	for _, sess := range us.sessions {
+		candidates = append(candidates, sess)
+	}
+	us.mu.Unlock()
+
+	removed := 0
+	for _, sess := range candidates {
+		// 正在写入的会话跳过，下次再检查
+		if !sess.mu.TryLock() {
+			continue
+		}
+		if now.Sub(sess.info.UpdatedAt) > ttl {
+			us.remove(sess.info.ID)
+			removed++
+		}
+		sess.mu.Unlock()
+	}
+	if removed > 0 {
+		logger.GetInstance().Info("🧹 清理过期上传会话: %d 个", removed)
+	}
+	return removed
+}
+
+// 定期清理过期的上传会话，启动时先清理一次停机期间过期的会话
+func (s *FileStore) uploadSessionGC() {
+	s.PruneUploadSessions(time.Now())
+	ticker := time.NewTicker(uploadGCInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.PruneUploadSessions(time.Now())
+		case <-s.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"file-ranking/internal/validation"
+)
+
+// 在指定目录中打开存储，重启测试用同一目录重新打开
+func openTestStore(t *testing.T, dir string) *FileStore {
+	t.Helper()
+	s, err := NewFileStore(filepath.Join(dir, "files.json"), filepath.Join(dir, "uploads"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestUploadChunkOffsets(t *testing.T) {
+	s := newTestStore(t)
+	sess, err := s.CreateUploadSession("a.txt", 10, RootFolderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.WriteUploadChunk(sess.ID, 0, strings.NewReader("0123")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		offset int64
+		data   string
+	}{
+		{"重复发送已接收的分片", 0, "0123"},
+		{"跳过一部分数据", 6, "6789"},
+	}
+	for _, tt := range tests {
+		got, err := s.WriteUploadChunk(sess.ID, tt.offset, strings.NewReader(tt.data))
+		var mismatch *OffsetMismatchError
+		if !errors.As(err, &mismatch) || mismatch.Expected != 4 {
+			t.Errorf("%s: err = %v, 期望偏移不匹配且已接收 4", tt.name, err)
+		}
+		if got == nil || got.Received != 4 {
+			t.Errorf("%s: 返回的进度 = %+v, 期望已接收 4", tt.name, got)
+		}
+	}
+
+	// 超出声明大小的分片整体拒绝，已接收的数据不变
+	got, err := s.WriteUploadChunk(sess.ID, 4, strings.NewReader("456789X"))
+	if err == nil {
+		t.Fatal("超出声明大小的分片应被拒绝")
+	}
+	if got.Received != 4 {
+		t.Fatalf("拒绝后已接收 = %d, 期望 4", got.Received)
+	}
+	if stat, err := os.Stat(s.uploads.partPath(sess.ID)); err != nil || stat.Size() != 4 {
+		t.Fatalf("拒绝后磁盘上的数据应为 4 字节: %v", err)
+	}
+
+	if got, err = s.WriteUploadChunk(sess.ID, 4, strings.NewReader("456789")); err != nil || got.Received != 10 {
+		t.Fatalf("继续上传: %+v, %v", got, err)
+	}
+}
+
+// 重启后从磁盘恢复会话，以实际写入的数据为准继续上传
+func TestUploadResumeAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestStore(t, dir)
+	sess, err := s.CreateUploadSession("resume.txt", 10, RootFolderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.WriteUploadChunk(sess.ID, 0, strings.NewReader("hello")); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	s = openTestStore(t, dir)
+	defer s.Close()
+	got, err := s.GetUploadSession(sess.ID)
+	if err != nil {
+		t.Fatalf("重启后查询会话: %v", err)
+	}
+	if got.Received != 5 || got.Size != 10 || got.Name != "resume.txt" {
+		t.Fatalf("重启后的会话 = %+v", got)
+	}
+	if _, err := s.WriteUploadChunk(sess.ID, 5, strings.NewReader("world")); err != nil {
+		t.Fatal(err)
+	}
+
+	outcome, err := s.FinalizeUpload(sess.ID, DuplicateKeep)
+	if err != nil {
+		t.Fatalf("FinalizeUpload: %v", err)
+	}
+	data, err := os.ReadFile(outcome.File.Path)
+	if err != nil || string(data) != "helloworld" {
+		t.Fatalf("上传的内容 = %q, %v", data, err)
+	}
+	if _, err := s.GetUploadSession(sess.ID); !errors.Is(err, ErrUploadSessionNotFound) {
+		t.Fatalf("完成后会话应被删除: %v", err)
+	}
+}
+
+func TestUploadSessionGC(t *testing.T) {
+	s := newTestStore(t)
+	if err := s.SetUploadSessionTTL(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	stale, err := s.CreateUploadSession("stale.txt", 10, RootFolderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh, err := s.CreateUploadSession("fresh.txt", 10, RootFolderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 有活动的会话从最后一次写入重新计时
+	later := time.Now().Add(50 * time.Minute)
+	sess, _ := s.uploads.get(fresh.ID)
+	sess.mu.Lock()
+	sess.info.UpdatedAt = later
+	sess.mu.Unlock()
+
+	if n := s.PruneUploadSessions(later.Add(20 * time.Minute)); n != 1 {
+		t.Fatalf("清理数量 = %d, 期望 1", n)
+	}
+	if _, err := s.GetUploadSession(stale.ID); !errors.Is(err, ErrUploadSessionNotFound) {
+		t.Fatalf("过期会话应被清理: %v", err)
+	}
+	for _, path := range []string{s.uploads.partPath(stale.ID), s.uploads.metaPath(stale.ID)} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("过期会话的文件应被删除: %s", path)
+		}
+	}
+	if _, err := s.GetUploadSession(fresh.ID); err != nil {
+		t.Fatalf("未过期的会话不应被清理: %v", err)
+	}
+}
+
+// 完成时未通过校验的数据被丢弃，会话随之结束，不登记文件
+func TestFinalizeUploadValidationFailure(t *testing.T) {
+	s := newTestStore(t)
+	s.SetUploadValidator(validation.Chain{validation.MagicBytes{}})
+
+	sess, err := s.CreateUploadSession("photo.png", 5, RootFolderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.WriteUploadChunk(sess.ID, 0, strings.NewReader("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.FinalizeUpload(sess.ID, DuplicateKeep)
+	var verr *validation.Error
+	if !errors.As(err, &verr) {
+		t.Fatalf("err = %v, 期望 *validation.Error", err)
+	}
+	if _, err := s.GetUploadSession(sess.ID); !errors.Is(err, ErrUploadSessionNotFound) {
+		t.Fatalf("校验失败后会话应被删除: %v", err)
+	}
+	if files := s.GetAllFiles(); len(files) != 0 {
+		t.Fatalf("校验失败不应登记文件: %+v", files)
+	}
+	entries, err := os.ReadDir(s.uploadDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			t.Errorf("校验失败后上传目录中残留文件: %s", e.Name())
+		}
+	}
+}
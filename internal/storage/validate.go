@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+
+	"file-ranking/internal/logger"
+	"file-ranking/internal/validation"
+)
+
+// 默认只限制大小，与之前的行为一致
+func defaultUploadValidator() validation.Validator {
+	return validation.Chain{
+		validation.SizeLimit{Max: validation.DefaultMaxSize, ResumableMax: MaxResumableSize},
+	}
+}
+
+// SetUploadValidator 设置上传文件的校验链，nil表示不校验
+func (s *FileStore) SetUploadValidator(v validation.Validator) {
+	s.mu.Lock()
+	s.validator = v
+	s.mu.Unlock()
+}
+
+func (s *FileStore) uploadValidator() validation.Validator {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.validator
+}
+
+// PrecheckUpload 写入前只根据文件名和大小校验，尽早拒绝不符合要求的上传；
+// 未通过时返回 *validation.Error
+func (s *FileStore) PrecheckUpload(name string, size int64, resumable bool) error {
+	err := validation.Check(context.Background(), s.uploadValidator(), &validation.Candidate{
+		Name:      name,
+		Size:      size,
+		Resumable: resumable,
+	})
+	if err != nil {
+		logger.GetInstance().Warn("🚫 上传预检未通过: %s - %v", name, err)
+	}
+	return err
+}
+
+// 文件写入磁盘后执行完整校验，调用方负责在未通过时删除文件
+func (s *FileStore) validateStored(file *FileData, resumable bool) error {
+	err := validation.Check(context.Background(), s.uploadValidator(), &validation.Candidate{
+		Name:      file.Name,
+		Size:      file.Size,
+		Resumable: resumable,
+		Path:      file.Path,
+		MimeType:  file.MimeType,
+		Text:      file.Encoding != "",
+	})
+	if err != nil {
+		logger.GetInstance().Warn("🚫 文件未通过校验: %s - %v", file.Name, err)
+	}
+	return err
+}
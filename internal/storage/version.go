@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+)
+
+// AnyVersion 不检查版本号，供导入同步、文件夹删除等内部调用使用
+const AnyVersion = 0
+
+// ErrVersionMismatch 写操作基于的版本号不是文件的当前版本，说明文件已被其他人修改
+var ErrVersionMismatch = errors.New("文件已被其他人修改，请刷新后重试")
+
+// 检查调用方基于的版本是否为文件的当前版本，调用方需持有锁
+func checkVersion(file *FileData, expected int) error {
+	if expected == AnyVersion || expected == file.Version {
+		return nil
+	}
+	return fmt.Errorf("%w (当前版本: %d, 请求版本: %d)", ErrVersionMismatch, file.Version, expected)
+}
@@ -0,0 +1,102 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"path"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// 有固定文件头的扩展名及其允许的实际类型，识别结果是其中之一或其子类型即可
+var signatures = map[string][]string{
+	".png":    {"image/png"},
+	".jpg":    {"image/jpeg"},
+	".jpeg":   {"image/jpeg"},
+	".gif":    {"image/gif"},
+	".webp":   {"image/webp"},
+	".bmp":    {"image/bmp"},
+	".ico":    {"image/x-icon"},
+	".tif":    {"image/tiff"},
+	".tiff":   {"image/tiff"},
+	".psd":    {"image/vnd.adobe.photoshop"},
+	".pdf":    {"application/pdf"},
+	".zip":    {"application/zip"},
+	".gz":     {"application/gzip"},
+	".tgz":    {"application/gzip"},
+	".tar":    {"application/x-tar"},
+	".7z":     {"application/x-7z-compressed"},
+	".rar":    {"application/x-rar-compressed"},
+	".docx":   {"application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+	".xlsx":   {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+	".pptx":   {"application/vnd.openxmlformats-officedocument.presentationml.presentation"},
+	".doc":    {"application/msword", "application/x-ole-storage"},
+	".xls":    {"application/vnd.ms-excel", "application/x-ole-storage"},
+	".ppt":    {"application/vnd.ms-powerpoint", "application/x-ole-storage"},
+	".odt":    {"application/vnd.oasis.opendocument.text"},
+	".ods":    {"application/vnd.oasis.opendocument.spreadsheet"},
+	".epub":   {"application/epub+zip"},
+	".mp3":    {"audio/mpeg"},
+	".wav":    {"audio/wav"},
+	".flac":   {"audio/flac"},
+	".ogg":    {"application/ogg"},
+	".mp4":    {"video/mp4", "audio/mp4"},
+	".mov":    {"video/quicktime"},
+	".avi":    {"video/x-msvideo"},
+	".mkv":    {"video/x-matroska"},
+	".webm":   {"video/webm"},
+	".exe":    {"application/vnd.microsoft.portable-executable"},
+	".sqlite": {"application/vnd.sqlite3"},
+}
+
+// 内容必须是文本的扩展名
+var textExtensions = map[string]bool{
+	".txt": true, ".md": true, ".markdown": true, ".csv": true, ".tsv": true,
+	".json": true, ".yaml": true, ".yml": true, ".xml": true, ".html": true,
+	".htm": true, ".log": true, ".ini": true, ".conf": true, ".svg": true,
+}
+
+// MagicBytes 检查文件头与扩展名是否一致，防止改扩展名绕过类型限制
+type MagicBytes struct{}
+
+func (MagicBytes) Validate(_ context.Context, c *Candidate) []Reason {
+	if c.Path == "" {
+		return nil
+	}
+	ext := strings.ToLower(path.Ext(c.Name))
+	detected := c.MimeType
+	if mediaType, _, err := mime.ParseMediaType(detected); err == nil {
+		detected = mediaType
+	}
+
+	if textExtensions[ext] {
+		if c.Text {
+			return nil
+		}
+		return []Reason{mismatch(ext, detected)}
+	}
+
+	expected, ok := signatures[ext]
+	if !ok {
+		return nil
+	}
+	for m := mimetype.Lookup(detected); m != nil; m = m.Parent() {
+		for _, want := range expected {
+			if m.Is(want) {
+				return nil
+			}
+		}
+	}
+	return []Reason{mismatch(ext, detected)}
+}
+
+func mismatch(ext, detected string) Reason {
+	return Reason{
+		Validator: "magic",
+		Code:      "content_mismatch",
+		Message:   fmt.Sprintf("文件内容与扩展名 %s 不符", ext),
+		Detail:    "实际类型: " + detected,
+	}
+}
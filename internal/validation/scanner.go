@@ -0,0 +1,100 @@
+package validation
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"file-ranking/internal/logger"
+)
+
+// DefaultScanTimeout 扫描命令的默认超时时间
+const DefaultScanTimeout = 60 * time.Second
+
+// 扫描输出只保留开头部分作为拒绝原因
+const maxScanOutput = 500
+
+// Scanner 调用外部命令扫描文件，例如 clamscan --no-summary {path}。
+// 参数中的 {path} 替换为文件路径，没有时把路径追加到最后；
+// 退出码0表示通过，1表示发现威胁，其他视为扫描失败，同样拒绝
+type Scanner struct {
+	Command []string
+	Timeout time.Duration
+}
+
+// ParseScanner 解析以空格分隔的扫描命令，命令为空时返回nil
+func ParseScanner(command string, timeout time.Duration) *Scanner {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil
+	}
+	if timeout <= 0 {
+		timeout = DefaultScanTimeout
+	}
+	return &Scanner{Command: args, Timeout: timeout}
+}
+
+func (s *Scanner) Validate(ctx context.Context, c *Candidate) []Reason {
+	if c.Path == "" || len(s.Command) == 0 {
+		return nil
+	}
+	log := logger.GetInstance()
+
+	args := make([]string, 0, len(s.Command)+1)
+	replaced := false
+	for _, arg := range s.Command[1:] {
+		if strings.Contains(arg, "{path}") {
+			arg = strings.ReplaceAll(arg, "{path}", c.Path)
+			replaced = true
+		}
+		args = append(args, arg)
+	}
+	if !replaced {
+		args = append(args, c.Path)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.Command[0], args...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := cmd.Run()
+	if err == nil {
+		return nil
+	}
+
+	// 输出中的存储路径替换为文件名，不暴露服务端目录
+	detail := strings.TrimSpace(strings.ReplaceAll(output.String(), c.Path, c.Name))
+	if len(detail) > maxScanOutput {
+		detail = detail[:maxScanOutput]
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && ctx.Err() == nil {
+		log.Warn("🛡️ 扫描发现威胁: %s - %s", c.Name, detail)
+		return []Reason{{
+			Validator: "scanner",
+			Code:      "infected",
+			Message:   "文件未通过安全扫描",
+			Detail:    detail,
+		}}
+	}
+
+	if ctx.Err() != nil {
+		err = fmt.Errorf("扫描超时 (%v)", s.Timeout)
+	}
+	log.Error("❌ 文件扫描失败: %s - %v", c.Name, err)
+	if detail == "" {
+		detail = err.Error()
+	}
+	return []Reason{{
+		Validator: "scanner",
+		Code:      "scan_failed",
+		Message:   "文件安全扫描失败",
+		Detail:    detail,
+	}}
+}
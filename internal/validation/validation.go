@@ -0,0 +1,165 @@
+// Package validation 上传文件的校验链：大小、扩展名、文件头和外部扫描命令
+package validation
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// DefaultMaxSize 普通上传的默认大小上限
+const DefaultMaxSize = 10 * 1024 * 1024
+
+// Candidate 待校验的文件；Path为空时只根据名称和大小预检，跳过需要读取内容的校验
+type Candidate struct {
+	Name string
+	Size int64
+	// 分片上传的文件适用单独的大小上限
+	Resumable bool
+
+	Path     string
+	MimeType string
+	// 内容是否为文本
+	Text bool
+}
+
+// Reason 拒绝原因
+type Reason struct {
+	Validator string `json:"validator"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// Error 文件未通过校验，包含所有拒绝原因
+type Error struct {
+	Name    string
+	Reasons []Reason
+}
+
+func (e *Error) Error() string {
+	messages := make([]string, len(e.Reasons))
+	for i, r := range e.Reasons {
+		messages[i] = r.Message
+	}
+	return fmt.Sprintf("文件未通过校验: %s", strings.Join(messages, "；"))
+}
+
+// Validator 校验一个文件，通过时返回nil
+type Validator interface {
+	Validate(ctx context.Context, c *Candidate) []Reason
+}
+
+// Chain 依次执行所有校验并汇总拒绝原因
+type Chain []Validator
+
+func (ch Chain) Validate(ctx context.Context, c *Candidate) []Reason {
+	var reasons []Reason
+	for _, v := range ch {
+		reasons = append(reasons, v.Validate(ctx, c)...)
+	}
+	return reasons
+}
+
+// Check 执行校验，未通过时返回 *Error
+func Check(ctx context.Context, v Validator, c *Candidate) error {
+	if v == nil {
+		return nil
+	}
+	if reasons := v.Validate(ctx, c); len(reasons) > 0 {
+		return &Error{Name: c.Name, Reasons: reasons}
+	}
+	return nil
+}
+
+// SizeLimit 文件大小上限，0表示不限制
+type SizeLimit struct {
+	Max          int64
+	ResumableMax int64
+}
+
+func (l SizeLimit) Validate(_ context.Context, c *Candidate) []Reason {
+	limit := l.Max
+	if c.Resumable {
+		limit = l.ResumableMax
+	}
+	if limit <= 0 || c.Size <= limit {
+		return nil
+	}
+	return []Reason{{
+		Validator: "size",
+		Code:      "too_large",
+		Message:   fmt.Sprintf("文件大小不能超过%s", formatSize(limit)),
+		Detail:    fmt.Sprintf("%d > %d bytes", c.Size, limit),
+	}}
+}
+
+// Extensions 扩展名白名单和黑名单，Allow非空时只接受其中的扩展名
+type Extensions struct {
+	Allow []string
+	Deny  []string
+}
+
+// ParseExtensions 解析逗号分隔的扩展名列表，统一为小写并带点
+func ParseExtensions(list string) []string {
+	var exts []string
+	for _, ext := range strings.Split(list, ",") {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		exts = append(exts, ext)
+	}
+	return exts
+}
+
+func (e Extensions) Validate(_ context.Context, c *Candidate) []Reason {
+	name := strings.ToLower(path.Base(strings.ReplaceAll(c.Name, "\\", "/")))
+	ext := path.Ext(name)
+	if matchExtension(name, e.Deny) {
+		return []Reason{{
+			Validator: "extension",
+			Code:      "extension_denied",
+			Message:   fmt.Sprintf("不允许上传 %s 类型的文件", ext),
+		}}
+	}
+	if len(e.Allow) > 0 && !matchExtension(name, e.Allow) {
+		message := "不允许上传没有扩展名的文件"
+		if ext != "" {
+			message = fmt.Sprintf("不允许上传 %s 类型的文件", ext)
+		}
+		return []Reason{{
+			Validator: "extension",
+			Code:      "extension_not_allowed",
+			Message:   message,
+			Detail:    "允许的扩展名: " + strings.Join(e.Allow, ", "),
+		}}
+	}
+	return nil
+}
+
+// 按后缀匹配，支持 .tar.gz 这样的多段扩展名
+func matchExtension(name string, exts []string) bool {
+	for _, ext := range exts {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<30 && n%(1<<30) == 0:
+		return fmt.Sprintf("%dGB", n>>30)
+	case n >= 1<<20:
+		return fmt.Sprintf("%dMB", n>>20)
+	case n >= 1<<10:
+		return fmt.Sprintf("%dKB", n>>10)
+	}
+	return fmt.Sprintf("%dB", n)
+}
@@ -3,24 +3,106 @@
 // API基础URL
 const API_BASE = '';
 
+// 在线编辑的最大文件大小，与服务端单次读取上限一致
+const MAX_EDIT_SIZE = 1024 * 1024;
 
+// 超过普通上传限制的文件改用分片上传，支持断点续传
+const CHUNKED_UPLOAD_THRESHOLD = 10 * 1024 * 1024;
+const UPLOAD_CHUNK_SIZE = 5 * 1024 * 1024;
+const UPLOAD_CHUNK_RETRIES = 3;
+// 勾选解压时压缩包通过普通上传提交，服务端限制100MB
+const MAX_ARCHIVE_UPLOAD_SIZE = 100 * 1024 * 1024;
 
-// 显示查看内容模态框
+
+
+// 显示查看内容模态框，支持预览的文件优先显示服务端渲染结果
 function showViewModal(fileId, fileName) {
     console.log('查看按钮点击触发，文件ID:', fileId, '文件名:', fileName);
-    
+    showPreview(fileId, fileName, 1);
+}
+
+function showPreview(fileId, fileName, page) {
+    fetch(`${API_BASE}/api/files/${fileId}/preview?page=${page}`)
+        .then(response => {
+            if (response.status === 415) {
+                // 不支持预览，显示原始文本
+                showRawContent(fileId, fileName);
+                return null;
+            }
+            return response.json();
+        })
+        .then(data => {
+            if (!data) {
+                return;
+            }
+            if (data.status !== 'success') {
+                showMessage('预览失败: ' + data.message, 'error');
+                return;
+            }
+
+            const result = data.data;
+            let pager = '';
+            if (result.table) {
+                const t = result.table;
+                pager = `
+                    <div class="preview-pager">
+                        <button type="button" class="btn-cancel" ${t.page <= 1 ? 'disabled' : ''} onclick="showPreview('${fileId}', '${escapeHtml(fileName)}', ${t.page - 1})">上一页</button>
+                        <span>第 ${t.page} / ${Math.max(t.total_pages, 1)} 页，共 ${t.total_rows} 行</span>
+                        <button type="button" class="btn-cancel" ${t.page >= t.total_pages ? 'disabled' : ''} onclick="showPreview('${fileId}', '${escapeHtml(fileName)}', ${t.page + 1})">下一页</button>
+                    </div>
+                `;
+            }
+
+            closeModal();
+            const modalHTML = `
+                <div class="modal" id="viewModal">
+                    <div class="modal-content" style="max-width: 900px; width: 95%;">
+                        <h2 class="modal-header">预览文件: ${escapeHtml(fileName)}</h2>
+                        <div class="preview-body preview-${result.kind}">${result.html}</div>
+                        ${pager}
+                        <div class="form-actions">
+                            <button type="button" class="btn-cancel" onclick="closeModal(); showRawContent('${fileId}', '${escapeHtml(fileName)}')">原始内容</button>
+                            <button type="button" class="btn-cancel" onclick="closeModal()">关闭</button>
+                        </div>
+                    </div>
+                </div>
+            `;
+            document.body.insertAdjacentHTML('beforeend', modalHTML);
+
+            document.getElementById('viewModal').addEventListener('click', function(e) {
+                if (e.target === this) {
+                    closeModal();
+                }
+            });
+        })
+        .catch(error => {
+            console.error('预览错误:', error);
+            showMessage('预览失败: ' + error.message, 'error');
+        });
+}
+
+// 显示原始文本内容
+function showRawContent(fileId, fileName) {
     // 先获取文件内容
     console.log('开始发送API请求到:', `${API_BASE}/api/files/${fileId}/content`);
     
     fetch(`${API_BASE}/api/files/${fileId}/content`)
         .then(response => {
             console.log('API响应状态:', response.status);
+            if (response.status === 415) {
+                // 二进制文件不能以文本查看，直接在浏览器中打开
+                window.open(`${API_BASE}/api/files/${fileId}/view`, '_blank');
+                return null;
+            }
             if (!response.ok) {
                 throw new Error(`HTTP ${response.status}: ${response.statusText}`);
             }
             return response.json();
         })
         .then(data => {
+            if (!data) {
+                return;
+            }
             console.log('API响应数据:', data);
             if (data.status === 'success') {
                 const modalHTML = `
@@ -28,8 +110,8 @@ function showViewModal(fileId, fileName) {
                         <div class="modal-content" style="max-width: 800px; width: 95%;">
                             <h2 class="modal-header">查看文件: ${escapeHtml(fileName)}</h2>
                             <div class="form-group">
-                                <label class="form-label">文件内容</label>
-                                <div class="file-content-view" style="
+                                <label class="form-label">文件内容 <span id="contentInfo" style="color: #888; font-weight: normal;"></span></label>
+                                <div class="file-content-view" id="fileContentView" style="
                                     background: rgba(248, 249, 250, 0.8);
                                     border: 1px solid #dee2e6;
                                     border-radius: 8px;
@@ -44,6 +126,7 @@ function showViewModal(fileId, fileName) {
                                 ">${escapeHtml(data.data.content || '(空文件)')}</div>
                             </div>
                             <div class="form-actions">
+                                <button type="button" class="btn-cancel" id="loadMoreBtn" style="display: none;" onclick="loadMoreContent('${fileId}')">加载更多</button>
                                 <button type="button" class="btn-cancel" onclick="closeModal()">关闭</button>
                                 <button type="button" class="btn-edit" onclick="showEditModal('${fileId}', '${escapeHtml(fileName)}'); closeModal();">编辑</button>
                             </div>
@@ -52,6 +135,7 @@ function showViewModal(fileId, fileName) {
                 `;
                 
                 document.body.insertAdjacentHTML('beforeend', modalHTML);
+                updateContentInfo(data.data);
                 
                 document.getElementById('viewModal').addEventListener('click', function(e) {
                     if (e.target === this) {
@@ -97,6 +181,11 @@ function setupEventListeners() {
         fileInput.addEventListener('change', handleFileSelect);
     }
     
+    const archiveBtn = document.getElementById('archiveBtn');
+    if (archiveBtn) {
+        archiveBtn.addEventListener('click', downloadSelectedArchive);
+    }
+
     // 创建文件按钮
     const createBtn = document.getElementById('createBtn');
     if (createBtn) {
@@ -118,35 +207,85 @@ function handleDragLeave(e) {
 function handleDrop(e) {
     e.preventDefault();
     e.currentTarget.classList.remove('dragover');
-    const files = Array.from(e.dataTransfer.files);
-    files.forEach(uploadFile);
+    uploadFiles(Array.from(e.dataTransfer.files));
 }
 
 function handleFileSelect(e) {
-    const files = Array.from(e.target.files);
-    files.forEach(uploadFile);
+    uploadFiles(Array.from(e.target.files));
+    e.target.value = '';
+}
+
+function duplicatePolicy() {
+    const select = document.getElementById('duplicatePolicy');
+    return select ? select.value : 'keep';
 }
 
-function uploadFile(file) {
+function isArchiveFile(name) {
+    return /\.(zip|tar\.gz|tgz)$/i.test(name);
+}
+
+// 大文件逐个分片上传，其余文件合并为一次请求
+function uploadFiles(files) {
+    if (files.length === 0) {
+        return;
+    }
+    const extractInput = document.getElementById('extractArchives');
+    const extract = extractInput && extractInput.checked;
+
+    const batch = [];
+    files.forEach(file => {
+        const archive = extract && isArchiveFile(file.name);
+        if (file.size > CHUNKED_UPLOAD_THRESHOLD && !(archive && file.size <= MAX_ARCHIVE_UPLOAD_SIZE)) {
+            uploadFileChunked(file);
+        } else {
+            batch.push(file);
+        }
+    });
+    if (batch.length === 0) {
+        return;
+    }
+
     const formData = new FormData();
-    formData.append('file', file);
-    
+    batch.forEach(file => formData.append('file', file));
+    if (extract) {
+        formData.append('extract', 'true');
+    }
+    formData.append('on_duplicate', duplicatePolicy());
+
     fetch(`${API_BASE}/api/files/upload`, {
         method: 'POST',
         body: formData
     })
     .then(response => {
-        if (!response.ok && response.status !== 0) {
+        // 400/422 为参数错误或未通过上传校验，响应中带有原因
+        if (!response.ok && response.status !== 0 && response.status !== 400 && response.status !== 422) {
             throw new Error('服务连接失败');
         }
         return response.json();
     })
     .then(data => {
-        if (data.status === 'success') {
-            showMessage('文件上传成功');
-            fetchData();
+        const results = data.data && data.data.results;
+        if (!results) {
+            if (data.status === 'success') {
+                showMessage(data.message);
+                fetchData();
+            } else {
+                showMessage('上传失败: ' + data.message, 'error');
+            }
+            return;
+        }
+
+        const failed = results.filter(r => r.status !== 'success');
+        const duplicates = results.filter(r => r.duplicate).length;
+        failed.forEach(r => console.warn('上传失败:', r.name, r.message));
+        if (failed.length === 0) {
+            showMessage(`${results.length} 个文件上传成功` + (duplicates > 0 ? `，其中 ${duplicates} 个与已有文件内容相同` : ''));
         } else {
-            showMessage('上传失败: ' + data.message, 'error');
+            const names = failed.slice(0, 3).map(r => `${r.name}（${r.message}）`).join('、');
+            showMessage(`${data.message}: ${names}${failed.length > 3 ? ' 等' : ''}`, 'error');
+        }
+        if (data.data.succeeded > 0) {
+            fetchData();
         }
     })
     .catch(error => {
@@ -157,19 +296,116 @@ function uploadFile(file) {
     });
 }
 
+// 同一文件的上传会话保存在本地，刷新页面后可以继续上传
+function uploadSessionKey(file) {
+    return `upload:${file.name}:${file.size}:${file.lastModified}`;
+}
+
+async function uploadFileChunked(file) {
+    const key = uploadSessionKey(file);
+    try {
+        let session = null;
+        const savedId = localStorage.getItem(key);
+        if (savedId) {
+            const response = await fetch(`${API_BASE}/api/uploads/${savedId}`);
+            if (response.ok) {
+                session = (await response.json()).data;
+                showMessage(`继续上传 ${file.name}（已完成 ${Math.floor(session.received * 100 / session.size)}%）`);
+            } else {
+                localStorage.removeItem(key);
+            }
+        }
+
+        if (!session) {
+            const response = await fetch(`${API_BASE}/api/uploads`, {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ name: file.name, size: file.size })
+            });
+            const data = await response.json();
+            if (data.status !== 'success') {
+                throw new Error(data.message);
+            }
+            session = data.data;
+            localStorage.setItem(key, session.id);
+        }
+
+        let offset = session.received;
+        let retries = 0;
+        while (offset < file.size) {
+            const chunk = file.slice(offset, offset + UPLOAD_CHUNK_SIZE);
+            try {
+                const response = await fetch(`${API_BASE}/api/uploads/${session.id}`, {
+                    method: 'PATCH',
+                    headers: {
+                        'Content-Type': 'application/octet-stream',
+                        'Upload-Offset': String(offset)
+                    },
+                    body: chunk
+                });
+                const data = await response.json();
+                if (response.status === 404) {
+                    localStorage.removeItem(key);
+                    throw new Error(data.message);
+                }
+                // 偏移不一致时以服务端记录为准
+                if (data.data) {
+                    offset = data.data.received;
+                }
+                if (!response.ok && response.status !== 409) {
+                    throw new Error(data.message);
+                }
+                retries = 0;
+            } catch (error) {
+                if (!localStorage.getItem(key) || ++retries > UPLOAD_CHUNK_RETRIES) {
+                    throw error;
+                }
+                // 网络中断后查询服务端进度再继续
+                await new Promise(resolve => setTimeout(resolve, 1000 * retries));
+                const response = await fetch(`${API_BASE}/api/uploads/${session.id}`);
+                if (response.ok) {
+                    offset = (await response.json()).data.received;
+                }
+            }
+        }
+
+        const response = await fetch(`${API_BASE}/api/uploads/${session.id}/finalize?on_duplicate=${duplicatePolicy()}`, { method: 'POST' });
+        const data = await response.json();
+        // 未通过校验时服务端已丢弃数据，不能再继续
+        if (response.status === 404 || response.status === 422) {
+            localStorage.removeItem(key);
+        }
+        if (data.status !== 'success') {
+            throw new Error(data.message);
+        }
+        localStorage.removeItem(key);
+        showMessage(data.message);
+        fetchData();
+    } catch (error) {
+        console.error('分片上传错误:', error);
+        showMessage('上传失败: ' + error.message + '，重新选择该文件可继续上传', 'error');
+    }
+}
+
 // 数据获取
 async function fetchData() {
     try {
-        const response = await fetch(`${API_BASE}/api/files`);
+        const [response, statsResponse] = await Promise.all([
+            fetch(`${API_BASE}/api/files`),
+            fetch(`${API_BASE}/api/stats`)
+        ]);
         if (!response.ok) {
             if (response.status === 0 || response.status === 502) {
                 return;
             }
         }
         const data = await response.json();
+        const stats = await statsResponse.json();
         
+        if (stats.status === 'success') {
+            updateStats(stats.data);
+        }
         if (data.status === 'success') {
-            updateStats(data.data);
             renderAllFiles(data.data);
             renderRankingList(data.data);
         }
@@ -205,11 +441,11 @@ function animateNumber(element, start, end, duration = 1000) {
     }, 16);
 }
 
-// 更新统计信息
-function updateStats(files) {
-    const totalCount = files.length;
-    const totalClicks = files.reduce((sum, file) => sum + file.clicks, 0);
-    const totalSize = files.reduce((sum, file) => sum + file.size, 0);
+// 更新统计信息（由 /api/stats 计算）
+function updateStats(stats) {
+    const totalCount = stats.total_files;
+    const totalClicks = stats.total_clicks;
+    const totalSize = stats.total_bytes;
     
     const countEl = document.getElementById('totalCount');
     const clicksEl = document.getElementById('totalClicks');
@@ -248,16 +484,27 @@ function renderAllFiles(files) {
         return;
     }
     
+    // 已删除的文件不再保留选中状态
+    const ids = new Set(files.map(file => file.id));
+    selectedFileIds.forEach(id => {
+        if (!ids.has(id)) {
+            selectedFileIds.delete(id);
+        }
+    });
+    updateArchiveButton();
+    files.forEach(file => fileVersions.set(file.id, file.version));
+
     // 按创建时间排序（最新的在前）
-    const sortedFiles = [...files].sort((a, b) => new Date(b.upload_at) - new Date(a.upload_at));
+    const sortedFiles = [...files].sort((a, b) => new Date(b.modified_at) - new Date(a.modified_at));
     
     container.innerHTML = sortedFiles.map(file => `
         <div class="file-item" onclick="incrementClick('${file.id}')">
             <div class="file-info">
-                <div class="file-name">${escapeHtml(file.name)}</div>
+                <div class="file-name"><input type="checkbox" class="file-select" ${selectedFileIds.has(file.id) ? 'checked' : ''} onclick="event.stopPropagation()" onchange="toggleFileSelection('${file.id}', this.checked)">${escapeHtml(file.name)}</div>
                 <div class="file-size">大小: ${formatFileSize(file.size)}</div>
-                <div class="file-date">修改时间: ${formatDate(file.upload_at)}</div>
+                <div class="file-date">修改时间: ${formatDate(file.modified_at)}</div>
                 <div class="file-clicks">点击次数: ${file.clicks}</div>
+                <div class="file-lock" data-file-id="${file.id}">${lockLabel(file.id)}</div>
                 <div class="file-actions" onclick="event.stopPropagation()">
                     <button class="btn-view" onclick="showViewModal('${file.id}', '${escapeHtml(file.name)}')" title="查看内容">查看</button>
                     <button class="btn-rename" onclick="showRenameModal('${file.id}', '${escapeHtml(file.name)}')" title="重命名">重命名</button>
@@ -269,6 +516,173 @@ function renderAllFiles(files) {
     `).join('');
 }
 
+// 打包下载选中的文件
+const selectedFileIds = new Set();
+
+// 文件列表中每个文件的版本，重命名、编辑和删除时作为 If-Match 发送，
+// 文件已被其他人修改时服务端返回412
+const fileVersions = new Map();
+
+function ifMatch(version) {
+    return version ? { 'If-Match': `"v${version}"` } : {};
+}
+
+// 其他人正在编辑的文件，由WebSocket实时更新
+const editLocks = new Map();
+// 当前打开的编辑框持有的编辑锁
+let heldEditLock = null;
+
+function lockLabel(fileId) {
+    const lock = editLocks.get(fileId);
+    // 释放消息可能丢失，已过期的锁不再显示
+    if (!lock || new Date(lock.expires_at) <= new Date()) {
+        return '';
+    }
+    return `✏️ ${escapeHtml(lock.holder)} 正在编辑`;
+}
+
+function updateLockBadge(fileId) {
+    document.querySelectorAll(`.file-lock[data-file-id="${fileId}"]`).forEach(el => {
+        el.innerHTML = lockLabel(fileId);
+    });
+}
+
+function setEditLock(fileId, lock) {
+    if (lock) {
+        editLocks.set(fileId, lock);
+    } else {
+        editLocks.delete(fileId);
+    }
+    updateLockBadge(fileId);
+}
+
+// 加载当前所有编辑锁，WebSocket（重新）连接时调用
+function fetchEditLocks() {
+    fetch(`${API_BASE}/api/locks`)
+        .then(response => response.json())
+        .then(data => {
+            if (data.status !== 'success') {
+                return;
+            }
+            const previous = [...editLocks.keys()];
+            editLocks.clear();
+            data.data.forEach(lock => editLocks.set(lock.file_id, lock));
+            new Set([...previous, ...editLocks.keys()]).forEach(updateLockBadge);
+        })
+        .catch(error => console.error('获取编辑锁错误:', error));
+}
+
+// 编辑者名称，首次编辑时询问并保存在本地
+function editorName() {
+    let name = localStorage.getItem('editorName');
+    if (!name) {
+        name = (prompt('请输入你的名字，其他人会看到你正在编辑') || '').trim() || '匿名用户';
+        localStorage.setItem('editorName', name);
+    }
+    return name;
+}
+
+// 获取编辑锁并在有效期内定时续期；文件正被其他人编辑时返回null
+async function acquireEditLock(fileId) {
+    const response = await fetch(`${API_BASE}/api/files/${fileId}/lock`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ holder: editorName() })
+    });
+    const data = await response.json();
+    if (data.status !== 'success') {
+        showMessage(data.message, 'error');
+        return null;
+    }
+
+    const lock = { fileId, token: data.data.token };
+    const ttl = new Date(data.data.expires_at) - new Date(data.data.acquired_at);
+    lock.timer = setInterval(() => {
+        fetch(`${API_BASE}/api/files/${fileId}/lock`, {
+            method: 'PUT',
+            headers: { 'X-Lock-Token': lock.token }
+        })
+        .then(response => {
+            if (response.status === 409) {
+                clearInterval(lock.timer);
+                showMessage('编辑锁已失效，保存时可能与他人的修改冲突', 'error');
+            }
+        })
+        .catch(error => console.error('编辑锁续期错误:', error));
+    }, Math.max(ttl / 3, 1000));
+    heldEditLock = lock;
+    return lock;
+}
+
+function releaseEditLock() {
+    const lock = heldEditLock;
+    if (!lock) {
+        return;
+    }
+    heldEditLock = null;
+    clearInterval(lock.timer);
+    fetch(`${API_BASE}/api/files/${lock.fileId}/lock`, {
+        method: 'DELETE',
+        headers: { 'X-Lock-Token': lock.token },
+        keepalive: true
+    }).catch(error => console.error('释放编辑锁错误:', error));
+}
+
+window.addEventListener('beforeunload', releaseEditLock);
+
+// 版本冲突时提示并刷新列表，返回true表示已处理
+function handleVersionConflict(response, data) {
+    if (response.status !== 412) {
+        return false;
+    }
+    showMessage((data && data.message) || '文件已被其他人修改，请刷新后重试', 'error');
+    closeModal();
+    fetchData();
+    return true;
+}
+
+function toggleFileSelection(fileId, checked) {
+    if (checked) {
+        selectedFileIds.add(fileId);
+    } else {
+        selectedFileIds.delete(fileId);
+    }
+    updateArchiveButton();
+}
+
+function updateArchiveButton() {
+    const button = document.getElementById('archiveBtn');
+    if (!button) return;
+    button.disabled = selectedFileIds.size === 0;
+    button.textContent = selectedFileIds.size > 0 ? `📦 打包下载选中 (${selectedFileIds.size})` : '📦 打包下载选中';
+}
+
+async function downloadSelectedArchive() {
+    if (selectedFileIds.size === 0) return;
+    try {
+        const response = await fetch(`${API_BASE}/api/files/archive`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ ids: Array.from(selectedFileIds) })
+        });
+        if (!response.ok) {
+            const data = await response.json();
+            throw new Error(data.message);
+        }
+        const disposition = response.headers.get('Content-Disposition') || '';
+        const match = disposition.match(/filename="?([^";]+)"?/);
+        const url = URL.createObjectURL(await response.blob());
+        const link = document.createElement('a');
+        link.href = url;
+        link.download = match ? match[1] : 'files.zip';
+        link.click();
+        URL.revokeObjectURL(url);
+    } catch (error) {
+        console.error('打包下载错误:', error);
+        showMessage('打包下载失败: ' + error.message, 'error');
+    }
+}
+
 // 渲染排行榜（按点击次数排序）
 function renderRankingList(files) {
     const container = document.getElementById('rankingList');
@@ -307,7 +721,7 @@ function renderRankingList(files) {
                         <div class="ranking-col name">${escapeHtml(file.name)}</div>
                         <div class="ranking-col clicks">${file.clicks}</div>
                         <div class="ranking-col size">${formatFileSize(file.size)}</div>
-                        <div class="ranking-col date">${formatDate(file.upload_at)}</div>
+                        <div class="ranking-col date">${formatDate(file.modified_at)}</div>
                     </div>
                 `;
             }).join('')}
@@ -337,12 +751,15 @@ function connectWebSocket() {
     
     ws.onopen = function() {
         console.log('WebSocket连接已建立');
+        fetchEditLocks();
     };
     
     ws.onmessage = function(event) {
         const data = JSON.parse(event.data);
         if (data.type === 'update') {
             fetchData();
+        } else if (data.type === 'lock') {
+            setEditLock(data.file_id, data.lock);
         }
     };
     
@@ -407,7 +824,7 @@ function createFile() {
         body: JSON.stringify({ name, content })
     })
     .then(response => {
-        if (!response.ok) {
+        if (!response.ok && response.status !== 422) {
             throw new Error(`HTTP ${response.status}: ${response.statusText}`);
         }
         return response.json();
@@ -430,6 +847,9 @@ function createFile() {
 function closeModal() {
     const modal = document.getElementById('createModal') || document.getElementById('renameModal') || document.getElementById('editModal') || document.getElementById('viewModal');
     if (modal) {
+        if (modal.id === 'editModal') {
+            releaseEditLock();
+        }
         modal.remove();
     }
 }
@@ -456,9 +876,11 @@ function showRenameModal(fileId, currentName) {
     
     document.body.insertAdjacentHTML('beforeend', modalHTML);
     
+    // 以打开对话框时看到的版本为准
+    const version = fileVersions.get(fileId);
     document.getElementById('renameForm').addEventListener('submit', function(e) {
         e.preventDefault();
-        renameFile(fileId);
+        renameFile(fileId, version);
     });
     
     document.getElementById('renameModal').addEventListener('click', function(e) {
@@ -469,23 +891,27 @@ function showRenameModal(fileId, currentName) {
 }
 
 // 重命名文件
-function renameFile(fileId) {
+function renameFile(fileId, version) {
     const newName = document.getElementById('newFileName').value;
     
     fetch(`${API_BASE}/api/files/${fileId}/rename`, {
         method: 'PUT',
         headers: {
             'Content-Type': 'application/json',
+            ...ifMatch(version),
         },
         body: JSON.stringify({ new_name: newName })
     })
-    .then(response => {
-        if (!response.ok) {
+    .then(async response => {
+        if (!response.ok && response.status !== 412) {
             throw new Error(`HTTP ${response.status}: ${response.statusText}`);
         }
-        return response.json();
+        return { response, data: await response.json() };
     })
-    .then(data => {
+    .then(({ response, data }) => {
+        if (handleVersionConflict(response, data)) {
+            return;
+        }
         if (data.status === 'success') {
             showMessage('重命名成功');
             closeModal();
@@ -500,10 +926,59 @@ function renameFile(fileId) {
     });
 }
 
+// 下一段内容的起始偏移
+let contentNextOffset = 0;
+
+// 更新已加载进度和“加载更多”按钮
+function updateContentInfo(chunk) {
+    contentNextOffset = chunk.next_offset;
+    const info = document.getElementById('contentInfo');
+    if (info) {
+        info.textContent = `（${chunk.total_lines} 行，已加载 ${formatFileSize(chunk.next_offset)} / ${formatFileSize(chunk.total_size)}）`;
+    }
+    const btn = document.getElementById('loadMoreBtn');
+    if (btn) {
+        btn.style.display = chunk.has_more ? '' : 'none';
+    }
+}
+
+// 按偏移继续读取下一段并追加显示
+function loadMoreContent(fileId) {
+    fetch(`${API_BASE}/api/files/${fileId}/content?offset=${contentNextOffset}`)
+        .then(response => response.json())
+        .then(data => {
+            if (data.status !== 'success') {
+                showMessage('获取文件内容失败: ' + data.message, 'error');
+                return;
+            }
+            const view = document.getElementById('fileContentView');
+            if (view) {
+                view.insertAdjacentText('beforeend', data.data.content);
+            }
+            updateContentInfo(data.data);
+        })
+        .catch(error => {
+            showMessage('获取文件内容失败: ' + error.message, 'error');
+        });
+}
+
 // 显示编辑内容模态框
-function showEditModal(fileId, fileName) {
-    // 先获取文件内容
-    fetch(`${API_BASE}/api/files/${fileId}/content`)
+async function showEditModal(fileId, fileName) {
+    // 先获取编辑锁，避免与他人同时编辑
+    let lock;
+    try {
+        lock = await acquireEditLock(fileId);
+    } catch (error) {
+        console.error('获取编辑锁错误:', error);
+        showMessage('获取编辑锁失败: ' + error.message, 'error');
+        return;
+    }
+    if (!lock) {
+        return;
+    }
+
+    // 再获取文件内容，在线编辑需要完整内容
+    fetch(`${API_BASE}/api/files/${fileId}/content?length=${MAX_EDIT_SIZE}`)
         .then(response => {
             if (!response.ok) {
                 throw new Error(`HTTP ${response.status}: ${response.statusText}`);
@@ -511,6 +986,11 @@ function showEditModal(fileId, fileName) {
             return response.json();
         })
         .then(data => {
+            if (data.status === 'success' && data.data.has_more) {
+                releaseEditLock();
+                showMessage('文件超过1MB，不支持在线编辑，请下载后修改', 'error');
+                return;
+            }
             if (data.status === 'success') {
                 const modalHTML = `
                     <div class="modal" id="editModal">
@@ -521,6 +1001,13 @@ function showEditModal(fileId, fileName) {
                                     <label class="form-label">文件内容</label>
                                     <textarea class="form-textarea" id="fileContentEdit" placeholder="输入文件内容">${escapeHtml(data.data.content || '')}</textarea>
                                 </div>
+                                <div class="form-group">
+                                    <label class="form-label">保存编码</label>
+                                    <select class="form-input" id="fileEncodingEdit">
+                                        <option value="">保持原编码（${escapeHtml(data.data.encoding)}）</option>
+                                        <option value="utf-8">转换为 UTF-8</option>
+                                    </select>
+                                </div>
                                 <div class="form-actions">
                                     <button type="button" class="btn-cancel" onclick="closeModal()">取消</button>
                                     <button type="submit" class="btn-create">保存</button>
@@ -532,9 +1019,11 @@ function showEditModal(fileId, fileName) {
                 
                 document.body.insertAdjacentHTML('beforeend', modalHTML);
                 
+                // 以读取内容时的版本为准，编辑期间文件被其他人修改时保存会失败
+                const version = data.data.version;
                 document.getElementById('editForm').addEventListener('submit', function(e) {
                     e.preventDefault();
-                    editFile(fileId, fileName);
+                    editFile(fileId, fileName, version);
                 });
                 
                 document.getElementById('editModal').addEventListener('click', function(e) {
@@ -543,34 +1032,43 @@ function showEditModal(fileId, fileName) {
                     }
                 });
             } else {
+                releaseEditLock();
                 showMessage('获取文件内容失败: ' + data.message, 'error');
             }
         })
         .catch(error => {
+            releaseEditLock();
             console.error('获取文件内容错误:', error);
             showMessage('获取文件内容失败: ' + error.message, 'error');
         });
 }
 
 // 编辑文件内容
-function editFile(fileId, fileName) {
+function editFile(fileId, fileName, version) {
     const content = document.getElementById('fileContentEdit').value;
+    const encoding = document.getElementById('fileEncodingEdit').value;
     
     // 使用新的更新API，保留原有文件信息（包括点击次数）
     fetch(`${API_BASE}/api/files/${fileId}/content/edit`, {
         method: 'PUT',
         headers: {
             'Content-Type': 'application/json',
+            'X-Lock-Token': heldEditLock ? heldEditLock.token : '',
+            ...ifMatch(version),
         },
-        body: JSON.stringify({ content: content })
+        body: JSON.stringify({ content: content, encoding: encoding })
     })
-    .then(response => {
-        if (!response.ok) {
+    .then(async response => {
+        // 编码转换失败、编辑锁被他人持有等错误需要显示服务端的错误信息
+        if (!response.ok && ![400, 412, 423].includes(response.status)) {
             throw new Error(`HTTP ${response.status}: ${response.statusText}`);
         }
-        return response.json();
+        return { response, data: await response.json() };
     })
-    .then(data => {
+    .then(({ response, data }) => {
+        if (handleVersionConflict(response, data)) {
+            return;
+        }
         if (data.status === 'success') {
             showMessage('文件内容更新成功');
             closeModal();
@@ -587,12 +1085,17 @@ function editFile(fileId, fileName) {
 
 // 删除文件
 function deleteFile(fileId) {
+    const version = fileVersions.get(fileId);
     if (confirm('确定要删除这个文件吗？此操作不可恢复。')) {
         fetch(`${API_BASE}/api/files/${fileId}`, {
-            method: 'DELETE'
+            method: 'DELETE',
+            headers: ifMatch(version)
         })
-        .then(response => response.json())
-        .then(data => {
+        .then(async response => ({ response, data: await response.json() }))
+        .then(({ response, data }) => {
+            if (handleVersionConflict(response, data)) {
+                return;
+            }
             if (data.status === 'success') {
                 showMessage('文件删除成功');
                 fetchData();